@@ -0,0 +1,191 @@
+// 使用方法：apply 子命令，原样执行 plan 子命令生成的计划文件
+// 执行: clickhouse-ttl-tool apply --plan plan.json
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"clickhouse-ttl-tool/pkg/executor"
+	"clickhouse-ttl-tool/pkg/plan"
+	"clickhouse-ttl-tool/pkg/reporter"
+	"clickhouse-ttl-tool/pkg/scanner"
+	"clickhouse-ttl-tool/pkg/state"
+
+	"github.com/spf13/cobra"
+)
+
+// applyPlanFile 要执行的计划文件
+var applyPlanFile string
+
+// applyCmd 执行计划命令
+var applyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "执行 plan 生成的计划文件",
+	Long: `原样执行计划文件中的 SQL，不重新检测时间字段或匹配策略。
+
+执行前会重新计算每个表的结构校验和，
+任一表的结构（列、引擎参数或 TTL）在生成计划后发生变化时拒绝执行。`,
+	Example: `  clickhouse-ttl-tool apply --plan plan.json --dry-run
+  clickhouse-ttl-tool apply --plan plan.json`,
+	RunE: runApply,
+}
+
+func init() {
+	applyCmd.Flags().StringVar(&applyPlanFile, "plan", "",
+		"plan 子命令生成的计划文件 (必填)")
+	applyCmd.MarkFlagRequired("plan")
+
+	rootCmd.AddCommand(applyCmd)
+}
+
+// runApply 执行计划函数
+func runApply(cmd *cobra.Command, args []string) error {
+	printHeader()
+
+	if err := cfg.ValidateConnection(); err != nil {
+		return fmt.Errorf("配置验证失败: %w", err)
+	}
+
+	if err := checkInteractive(); err != nil {
+		return err
+	}
+
+	p, err := plan.Load(applyPlanFile)
+	if err != nil {
+		return fmt.Errorf("加载计划失败: %w", err)
+	}
+
+	entries := p.Actionable()
+	fmt.Fprintf(progressOut, "\n计划 %s（生成于 %s）: 共 %d 个表，需要执行 %d 个\n",
+		applyPlanFile, p.CreatedAt.Format("2006-01-02 15:04:05"), len(p.Entries), len(entries))
+	if len(entries) == 0 {
+		fmt.Fprintln(progressOut, "\n⚠ 计划中没有需要执行的表")
+		return nil
+	}
+
+	cli, err := connect()
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	ctx := context.Background()
+
+	// 使用生成计划时的集群，SQL 中已包含对应的 ON CLUSTER
+	if cfg.Cluster != "" && cfg.Cluster != p.Cluster {
+		fmt.Fprintf(progressOut, "⚠ 忽略 --cluster %s，使用计划中的集群 %q\n", cfg.Cluster, p.Cluster)
+	}
+	tracker, err := newTracker(ctx, cli, p.Cluster)
+	if err != nil {
+		return err
+	}
+
+	// 校验表结构是否在生成计划后发生变化
+	fmt.Fprintln(progressOut, "\n正在校验表结构...")
+	if err := verifyPlan(ctx, scanner.NewScanner(cli, scanner.Filter{}, nil).Checksum, entries); err != nil {
+		return err
+	}
+	fmt.Fprintln(progressOut, "✓ 表结构与计划一致")
+
+	if cfg.DryRun {
+		fmt.Fprintln(progressOut, "\n⚠️  预览模式：将显示 SQL 语句但不实际执行")
+	} else {
+		databases := planDatabases(entries)
+		fmt.Fprintln(progressOut, "\n"+strings.Repeat("=", 60))
+		fmt.Fprintln(progressOut, "⚠️  危险操作警告")
+		fmt.Fprintln(progressOut, strings.Repeat("=", 60))
+		fmt.Fprintf(progressOut, "\n将要执行计划 %s:\n", applyPlanFile)
+		fmt.Fprintf(progressOut, "  • 数据库: %s\n", strings.Join(databases, ", "))
+		fmt.Fprintf(progressOut, "  • 影响表数: %d 个\n", len(entries))
+		if tracker != nil {
+			fmt.Fprintf(progressOut, "  • 集群: %s (%d 个节点)\n", tracker.Name(), len(tracker.Hosts()))
+		}
+		token := confirmToken(databases)
+		fmt.Fprintf(progressOut, "\n请输入 '%s' 以确认操作: ", token)
+
+		ok, err := confirm(token)
+		if err != nil || !ok {
+			return err
+		}
+	}
+
+	var recorder *state.Recorder
+	if !cfg.DryRun {
+		recorder = state.NewRecorder(state.NewStore(cfg.StateDir), state.NewRunID(), p.Cluster)
+	}
+
+	rep := reporter.NewReporter(progressOut, cfg.Verbose, cfg.DryRun)
+	exec := executor.NewExecutor(cli, executor.Options{
+		DryRun:        cfg.DryRun,
+		Verbose:       cfg.Verbose,
+		Cluster:       tracker,
+		Recorder:      recorder,
+		Throttle:      newThrottle(cli),
+		Mutations:     newWatcher(cli, tracker, rep),
+		NoMaterialize: cfg.NoMaterialize,
+	})
+
+	fmt.Fprint(progressOut, "\n开始执行计划...\n\n")
+	forEachOrdered(len(entries), cfg.Concurrency,
+		func(i int) executor.ExecutionResult {
+			return exec.Apply(ctx, entries[i].Result())
+		},
+		func(i int, result executor.ExecutionResult) {
+			rep.AddResult(result)
+			rep.PrintProgress(i+1, len(entries), result)
+		})
+
+	summary := rep.PrintSummary()
+	printRollbackHint(recorder)
+	if err := exportReport(rep, summary); err != nil {
+		return err
+	}
+
+	if summary.Failed > 0 {
+		return errors.New("部分表执行失败")
+	}
+
+	return nil
+}
+
+// verifyPlan 校验计划中每个表的结构校验和，任一不一致时返回错误
+// checksum 返回表当前的结构校验和，通常为 Scanner.Checksum
+func verifyPlan(ctx context.Context, checksum func(ctx context.Context, database, table string) (string, error), entries []plan.Entry) error {
+	var changed []string
+	for _, entry := range entries {
+		current, err := checksum(ctx, entry.Database, entry.Table)
+		if err != nil {
+			changed = append(changed, fmt.Sprintf("%s.%s: %v", entry.Database, entry.Table, err))
+			continue
+		}
+		if current != entry.Checksum {
+			changed = append(changed, fmt.Sprintf("%s.%s: 表结构已变化", entry.Database, entry.Table))
+		}
+	}
+
+	if len(changed) == 0 {
+		return nil
+	}
+
+	fmt.Fprintln(progressOut, "\n✗ 以下表在生成计划后发生了变化:")
+	for _, c := range changed {
+		fmt.Fprintf(progressOut, "  - %s\n", c)
+	}
+	return fmt.Errorf("%d 个表的结构与计划不一致，请重新生成计划", len(changed))
+}
+
+// planDatabases 返回计划条目涉及的数据库（按首次出现顺序）
+func planDatabases(entries []plan.Entry) []string {
+	var databases []string
+	seen := make(map[string]bool)
+	for _, e := range entries {
+		if !seen[e.Database] {
+			seen[e.Database] = true
+			databases = append(databases, e.Database)
+		}
+	}
+	return databases
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"clickhouse-ttl-tool/pkg/plan"
+)
+
+func TestVerifyPlan(t *testing.T) {
+	saved := progressOut
+	defer func() { progressOut = saved }()
+
+	current := map[string]string{
+		"logs.events":  "aaa",
+		"logs.metrics": "bbb",
+	}
+	checksum := func(_ context.Context, database, table string) (string, error) {
+		sum, ok := current[database+"."+table]
+		if !ok {
+			return "", errors.New("table not found")
+		}
+		return sum, nil
+	}
+
+	tests := []struct {
+		name    string
+		entries []plan.Entry
+		want    []string // 输出中应包含的表
+	}{
+		{
+			name: "unchanged",
+			entries: []plan.Entry{
+				{Database: "logs", Table: "events", Checksum: "aaa"},
+				{Database: "logs", Table: "metrics", Checksum: "bbb"},
+			},
+		},
+		{
+			name: "schema changed",
+			entries: []plan.Entry{
+				{Database: "logs", Table: "events", Checksum: "aaa"},
+				{Database: "logs", Table: "metrics", Checksum: "old"},
+			},
+			want: []string{"logs.metrics: 表结构已变化"},
+		},
+		{
+			name: "table dropped",
+			entries: []plan.Entry{
+				{Database: "logs", Table: "gone", Checksum: "ccc"},
+			},
+			want: []string{"logs.gone: table not found"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			progressOut = &buf
+
+			err := verifyPlan(context.Background(), checksum, tt.entries)
+			if (err != nil) != (len(tt.want) > 0) {
+				t.Fatalf("verifyPlan() error = %v, want error %v", err, len(tt.want) > 0)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(buf.String(), w) {
+					t.Errorf("output %q does not contain %q", buf.String(), w)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+// 使用方法：materialize 子命令，逐个分区按当前 TTL 重写已有数据
+// 执行: clickhouse-ttl-tool materialize --database my_db [--partition <分区 ID>] [--dry-run]
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"clickhouse-ttl-tool/pkg/executor"
+	"clickhouse-ttl-tool/pkg/reporter"
+	"clickhouse-ttl-tool/pkg/scanner"
+
+	"github.com/spf13/cobra"
+)
+
+// materializePartitions 仅物化指定的分区 ID，为空时物化所有分区
+var materializePartitions []string
+
+// materializeCmd 物化 TTL 命令
+var materializeCmd = &cobra.Command{
+	Use:   "materialize",
+	Short: "逐个分区物化匹配表的 TTL",
+	Long: `按与根命令相同的方式扫描表（--database、--all-databases、--include、--exclude），
+对每个已有 TTL 的表逐个分区执行 ALTER TABLE ... MATERIALIZE TTL IN PARTITION ID ...，
+按当前 TTL 删除、移动或重新压缩已有数据。
+
+配合 --no-materialize 使用：先只修改 TTL 定义，再用本命令分批重写数据。
+每个分区执行前都会按 --throttle-* 参数检查服务端负载，避免一次性重写整个表。`,
+	Example: `  # 预览
+  clickhouse-ttl-tool materialize --database my_db --include 'log_*' --dry-run
+
+  # 只物化指定分区
+  clickhouse-ttl-tool materialize --database my_db --include events --partition 202401 --partition 202402`,
+	RunE: runMaterialize,
+}
+
+func init() {
+	addTargetFlags(materializeCmd)
+
+	materializeCmd.Flags().StringSliceVar(&materializePartitions, "partition", nil,
+		"只物化指定的分区 ID（system.parts.partition_id），可逗号分隔或重复指定")
+
+	rootCmd.AddCommand(materializeCmd)
+}
+
+// runMaterialize 物化 TTL 执行函数
+func runMaterialize(cmd *cobra.Command, args []string) error {
+	printHeader()
+
+	if err := cfg.ValidateTargets(); err != nil {
+		return fmt.Errorf("配置验证失败: %w", err)
+	}
+
+	if err := checkInteractive(); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	sess, err := scanTargets(ctx, nil)
+	if err != nil || sess == nil {
+		return err
+	}
+	defer sess.Close()
+
+	if cfg.DryRun {
+		fmt.Fprintln(progressOut, "\n⚠️  预览模式：将显示 SQL 语句但不实际执行")
+	} else {
+		fmt.Fprintln(progressOut, "\n"+strings.Repeat("=", 60))
+		fmt.Fprintln(progressOut, "⚠️  危险操作警告")
+		fmt.Fprintln(progressOut, strings.Repeat("=", 60))
+		fmt.Fprintf(progressOut, "\n将要执行的操作:\n")
+		fmt.Fprintf(progressOut, "  • 数据库: %s\n", strings.Join(sess.databases, ", "))
+		fmt.Fprintf(progressOut, "  • 影响表数: %d 个\n", len(sess.tables))
+		if sess.tracker != nil {
+			fmt.Fprintf(progressOut, "  • 集群: %s (%d 个节点)\n", sess.tracker.Name(), len(sess.tracker.Hosts()))
+		}
+		if len(materializePartitions) > 0 {
+			fmt.Fprintf(progressOut, "  • 分区: %s\n", strings.Join(materializePartitions, ", "))
+		}
+		fmt.Fprintf(progressOut, "  • 操作类型: 物化 TTL（超过保留期的数据将被立即删除，重写数据会占用磁盘 IO）\n")
+		token := confirmToken(sess.databases)
+		if len(sess.databases) == 1 {
+			fmt.Fprintf(progressOut, "\n请输入数据库名 '%s' 以确认操作: ", token)
+		} else {
+			fmt.Fprintf(progressOut, "\n将影响 %d 个数据库，请输入 '%s' 以确认操作: ", len(sess.databases), token)
+		}
+
+		ok, err := confirm(token)
+		if err != nil || !ok {
+			return err
+		}
+	}
+
+	rep := reporter.NewReporter(progressOut, cfg.Verbose, cfg.DryRun)
+	exec := executor.NewExecutor(sess.cli, executor.Options{
+		DryRun:    cfg.DryRun,
+		Verbose:   cfg.Verbose,
+		Cluster:   sess.tracker,
+		Throttle:  newThrottle(sess.cli),
+		Mutations: newWatcher(sess.cli, sess.tracker, rep),
+	})
+
+	fmt.Fprint(progressOut, "\n开始物化 TTL...\n\n")
+	tables := sess.tables
+	forEachOrdered(len(tables), cfg.Concurrency,
+		func(i int) executor.ExecutionResult {
+			return materializeTable(ctx, sess, exec, tables[i])
+		},
+		func(i int, result executor.ExecutionResult) {
+			rep.AddResult(result)
+			rep.PrintProgress(i+1, len(tables), result)
+		})
+
+	summary := rep.PrintSummary()
+	if err := exportReport(rep, summary); err != nil {
+		return err
+	}
+
+	if summary.Failed > 0 {
+		return errors.New("部分表执行失败")
+	}
+
+	if cfg.DryRun {
+		fmt.Fprintln(progressOut, "\n提示：去掉 --dry-run 参数以实际执行")
+	}
+
+	return nil
+}
+
+// materializeTable 查询表的分区并逐个物化 TTL
+func materializeTable(
+	ctx context.Context,
+	sess *session,
+	exec *executor.Executor,
+	table scanner.TableInfo,
+) executor.ExecutionResult {
+	cluster := ""
+	if sess.tracker != nil {
+		cluster = sess.tracker.Name()
+	}
+
+	partitions, err := sess.scn.Partitions(ctx, table.Database, table.Table, cluster)
+	if err != nil {
+		return executor.ExecutionResult{
+			Database: table.Database,
+			Table:    table.Table,
+			Error:    err,
+		}
+	}
+
+	return exec.Materialize(ctx, table, filterPartitions(partitions, materializePartitions))
+}
+
+// filterPartitions 返回 partitions 中属于 wanted 的分区，wanted 为空时返回全部
+func filterPartitions(partitions, wanted []string) []string {
+	if len(wanted) == 0 {
+		return partitions
+	}
+
+	allowed := make(map[string]bool, len(wanted))
+	for _, p := range wanted {
+		allowed[p] = true
+	}
+
+	var filtered []string
+	for _, p := range partitions {
+		if allowed[p] {
+			filtered = append(filtered, p)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,79 @@
+// 使用方法：plan 子命令，扫描并生成执行计划文件，不修改任何表
+// 执行: clickhouse-ttl-tool plan --database my_db --policy policy.yaml --out plan.json
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"clickhouse-ttl-tool/pkg/executor"
+	"clickhouse-ttl-tool/pkg/plan"
+	"clickhouse-ttl-tool/pkg/reporter"
+
+	"github.com/spf13/cobra"
+)
+
+// planOut 计划文件输出路径
+var planOut string
+
+// planCmd 生成计划命令
+var planCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "生成 TTL 执行计划文件（不修改任何表）",
+	Long: `按与根命令相同的方式扫描表、匹配策略并检测时间字段，
+将每个表的时间字段、原 TTL、新 TTL SQL 和表结构校验和写入计划文件。
+
+计划文件可以提交评审，再由 apply 子命令原样执行。`,
+	Example: `  clickhouse-ttl-tool plan --database my_db --policy policy.yaml --out plan.json
+  clickhouse-ttl-tool apply --plan plan.json`,
+	RunE: runPlan,
+}
+
+func init() {
+	addScanFlags(planCmd)
+
+	planCmd.Flags().StringVar(&planOut, "out", "ttl-plan.json",
+		"计划文件输出路径")
+
+	rootCmd.AddCommand(planCmd)
+}
+
+// runPlan 生成计划执行函数
+func runPlan(cmd *cobra.Command, args []string) error {
+	printHeader()
+
+	// 生成计划不修改任何表
+	cfg.DryRun = true
+
+	ctx := context.Background()
+
+	sess, err := openSession(ctx)
+	if err != nil || sess == nil {
+		return err
+	}
+	defer sess.Close()
+
+	exec := executor.NewExecutor(sess.cli, executor.Options{
+		DryRun:  true,
+		Verbose: cfg.Verbose,
+		Cluster: sess.tracker,
+	})
+	rep := reporter.NewReporter(progressOut, cfg.Verbose, true)
+
+	fmt.Fprint(progressOut, "\n开始生成计划...\n\n")
+	processTables(ctx, sess, exec, rep)
+	summary := rep.PrintSummary()
+	if err := exportReport(rep, summary); err != nil {
+		return err
+	}
+
+	p := plan.FromResults(rep.GetResults(), cfg.Cluster, sess.databases)
+	if err := p.Save(planOut); err != nil {
+		return fmt.Errorf("保存计划失败: %w", err)
+	}
+
+	fmt.Fprintf(progressOut, "\n✓ 计划已写入 %s（%d 个表需要执行）\n", planOut, len(p.Actionable()))
+	fmt.Fprintf(progressOut, "提示：评审后使用 clickhouse-ttl-tool apply --plan %s 执行\n", planOut)
+
+	return nil
+}
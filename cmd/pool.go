@@ -0,0 +1,71 @@
+// 使用方法：以有限数量的 goroutine 并发处理表，并按原始顺序输出结果
+// 由 --concurrency 控制并发数，为 1 时顺序执行
+package cmd
+
+import (
+	"sync"
+
+	"clickhouse-ttl-tool/pkg/executor"
+)
+
+// forEachOrdered 使用 workers 个 goroutine 并发执行 fn(0..n-1)
+// emit 在调用方的 goroutine 中按下标顺序调用，保证进度输出和报告顺序稳定
+func forEachOrdered(
+	n, workers int,
+	fn func(i int) executor.ExecutionResult,
+	emit func(i int, result executor.ExecutionResult),
+) {
+	if workers > n {
+		workers = n
+	}
+	if workers <= 1 {
+		for i := 0; i < n; i++ {
+			emit(i, fn(i))
+		}
+		return
+	}
+
+	type item struct {
+		index  int
+		result executor.ExecutionResult
+	}
+
+	jobs := make(chan int)
+	done := make(chan item, workers)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				done <- item{index: i, result: fn(i)}
+			}
+		}()
+	}
+
+	go func() {
+		for i := 0; i < n; i++ {
+			jobs <- i
+		}
+		close(jobs)
+		wg.Wait()
+		close(done)
+	}()
+
+	// 先完成的结果暂存，等前面的结果都输出后再按顺序输出
+	pending := make(map[int]executor.ExecutionResult)
+	next := 0
+	for it := range done {
+		pending[it.index] = it.result
+		for {
+			result, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			emit(next, result)
+			next++
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"math/rand"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"clickhouse-ttl-tool/pkg/executor"
+)
+
+func TestForEachOrdered(t *testing.T) {
+	tests := []struct {
+		name       string
+		n, workers int
+	}{
+		{"empty", 0, 4},
+		{"sequential", 5, 1},
+		{"workers exceed tables", 3, 8},
+		{"concurrent", 50, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var running, peak int32
+			var emitted []int
+
+			forEachOrdered(tt.n, tt.workers,
+				func(i int) executor.ExecutionResult {
+					cur := atomic.AddInt32(&running, 1)
+					for {
+						p := atomic.LoadInt32(&peak)
+						if cur <= p || atomic.CompareAndSwapInt32(&peak, p, cur) {
+							break
+						}
+					}
+					// 随机耗时，使后面的表可能先完成
+					time.Sleep(time.Duration(rand.Intn(2000)) * time.Microsecond)
+					atomic.AddInt32(&running, -1)
+					return executor.ExecutionResult{Table: string(rune('a' + i%26))}
+				},
+				func(i int, result executor.ExecutionResult) {
+					if want := string(rune('a' + i%26)); result.Table != want {
+						t.Errorf("emit(%d) got result of %q, want %q", i, result.Table, want)
+					}
+					emitted = append(emitted, i)
+				})
+
+			if len(emitted) != tt.n {
+				t.Fatalf("emitted %d results, want %d", len(emitted), tt.n)
+			}
+			for i, v := range emitted {
+				if v != i {
+					t.Fatalf("emitted out of order: %v", emitted)
+				}
+			}
+			if int(peak) > tt.workers {
+				t.Errorf("peak concurrency %d exceeds workers %d", peak, tt.workers)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+// 使用方法：remove 子命令，移除匹配表的 TTL
+// 执行: clickhouse-ttl-tool remove --database my_db [--columns-only | --all] [--dry-run]
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"clickhouse-ttl-tool/pkg/executor"
+	"clickhouse-ttl-tool/pkg/reporter"
+	"clickhouse-ttl-tool/pkg/state"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	// removeColumnsOnly 是否只移除列级 TTL
+	removeColumnsOnly bool
+	// removeAll 是否同时移除表级和列级 TTL
+	removeAll bool
+)
+
+// removeCmd 移除 TTL 命令
+var removeCmd = &cobra.Command{
+	Use:   "remove",
+	Short: "移除匹配表的 TTL",
+	Long: `按与根命令相同的方式扫描表（--database、--all-databases、--include、--exclude），
+对每个表执行 ALTER TABLE ... REMOVE TTL，只移除表级 TTL，列级 TTL（如 PII 清理）保留。
+
+指定 --columns-only 时保留表级 TTL，只移除列级 TTL；
+指定 --all 时同时移除表级和所有列级 TTL。
+修改前的 TTL 同样记录到状态目录，可通过 rollback 子命令恢复。`,
+	Example: `  # 预览
+  clickhouse-ttl-tool remove --database my_db --include 'log_*' --dry-run
+
+  # 只移除列级 TTL
+  clickhouse-ttl-tool remove --database my_db --columns-only
+
+  # 移除表级和列级 TTL
+  clickhouse-ttl-tool remove --database my_db --all`,
+	RunE: runRemove,
+}
+
+func init() {
+	addTargetFlags(removeCmd)
+
+	removeCmd.Flags().BoolVar(&removeColumnsOnly, "columns-only", false,
+		"只移除列级 TTL，保留表级 TTL")
+
+	removeCmd.Flags().BoolVar(&removeAll, "all", false,
+		"同时移除表级和所有列级 TTL（默认只移除表级 TTL）")
+
+	rootCmd.AddCommand(removeCmd)
+}
+
+// runRemove 移除 TTL 执行函数
+func runRemove(cmd *cobra.Command, args []string) error {
+	printHeader()
+
+	if err := cfg.ValidateTargets(); err != nil {
+		return fmt.Errorf("配置验证失败: %w", err)
+	}
+
+	if removeColumnsOnly && removeAll {
+		return errors.New("--columns-only 和 --all 不能同时指定")
+	}
+
+	if err := checkInteractive(); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	sess, err := scanTargets(ctx, nil)
+	if err != nil || sess == nil {
+		return err
+	}
+	defer sess.Close()
+
+	scope, target := executor.RemoveTable, "表级 TTL"
+	switch {
+	case removeColumnsOnly:
+		scope, target = executor.RemoveColumns, "列级 TTL"
+	case removeAll:
+		scope, target = executor.RemoveAll, "表级和列级 TTL"
+	}
+
+	if cfg.DryRun {
+		fmt.Fprintln(progressOut, "\n⚠️  预览模式：将显示 SQL 语句但不实际执行")
+	} else {
+		fmt.Fprintln(progressOut, "\n"+strings.Repeat("=", 60))
+		fmt.Fprintln(progressOut, "⚠️  危险操作警告")
+		fmt.Fprintln(progressOut, strings.Repeat("=", 60))
+		fmt.Fprintf(progressOut, "\n将要执行的操作:\n")
+		fmt.Fprintf(progressOut, "  • 数据库: %s\n", strings.Join(sess.databases, ", "))
+		fmt.Fprintf(progressOut, "  • 影响表数: %d 个\n", len(sess.tables))
+		if sess.tracker != nil {
+			fmt.Fprintf(progressOut, "  • 集群: %s (%d 个节点)\n", sess.tracker.Name(), len(sess.tracker.Hosts()))
+		}
+		fmt.Fprintf(progressOut, "  • 操作类型: 移除%s（数据将不再自动过期）\n", target)
+		token := confirmToken(sess.databases)
+		if len(sess.databases) == 1 {
+			fmt.Fprintf(progressOut, "\n请输入数据库名 '%s' 以确认操作: ", token)
+		} else {
+			fmt.Fprintf(progressOut, "\n将影响 %d 个数据库，请输入 '%s' 以确认操作: ", len(sess.databases), token)
+		}
+
+		ok, err := confirm(token)
+		if err != nil || !ok {
+			return err
+		}
+	}
+
+	var recorder *state.Recorder
+	if !cfg.DryRun {
+		recorder = state.NewRecorder(state.NewStore(cfg.StateDir), state.NewRunID(), cfg.Cluster)
+	}
+
+	exec := executor.NewExecutor(sess.cli, executor.Options{
+		DryRun:   cfg.DryRun,
+		Verbose:  cfg.Verbose,
+		Cluster:  sess.tracker,
+		Recorder: recorder,
+		Throttle: newThrottle(sess.cli),
+	})
+	rep := reporter.NewReporter(progressOut, cfg.Verbose, cfg.DryRun)
+
+	fmt.Fprintf(progressOut, "\n开始移除%s...\n\n", target)
+	tables := sess.tables
+	forEachOrdered(len(tables), cfg.Concurrency,
+		func(i int) executor.ExecutionResult {
+			return exec.Remove(ctx, tables[i], scope)
+		},
+		func(i int, result executor.ExecutionResult) {
+			rep.AddResult(result)
+			rep.PrintProgress(i+1, len(tables), result)
+		})
+
+	summary := rep.PrintSummary()
+	printRollbackHint(recorder)
+	if err := exportReport(rep, summary); err != nil {
+		return err
+	}
+
+	if summary.Failed > 0 {
+		return errors.New("部分表执行失败")
+	}
+
+	if cfg.DryRun {
+		fmt.Fprintln(progressOut, "\n提示：去掉 --dry-run 参数以实际执行")
+	}
+
+	return nil
+}
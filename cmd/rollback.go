@@ -0,0 +1,194 @@
+// 使用方法：rollback 子命令，恢复某次运行修改前的 TTL
+// 执行: clickhouse-ttl-tool rollback --run <运行 ID> [--dry-run]
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"clickhouse-ttl-tool/pkg/executor"
+	"clickhouse-ttl-tool/pkg/reporter"
+	"clickhouse-ttl-tool/pkg/scanner"
+	"clickhouse-ttl-tool/pkg/state"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	// rollbackRunID 要回滚的运行 ID
+	rollbackRunID string
+	// rollbackList 是否仅列出可回滚的运行
+	rollbackList bool
+	// rollbackForce 表的 TTL 在运行之后被修改过时仍然回滚
+	rollbackForce bool
+)
+
+// rollbackCmd 回滚命令
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback",
+	Short: "恢复某次运行修改前的 TTL",
+	Long: `根据运行状态文件，将某次运行修改过的表恢复为修改前的 TTL。
+
+原本有 TTL 的表重新执行 MODIFY TTL <原 TTL>，
+原本没有 TTL 的表执行 REMOVE TTL。
+运行时使用了 --cluster 的，回滚同样以 ON CLUSTER 方式执行。
+
+回滚前会读取表当前的 TTL，与运行记录设置的 TTL 不一致（运行之后又被修改过）时
+拒绝回滚该表，使用 --force 仍然覆盖。`,
+	Example: `  # 列出可回滚的运行
+  clickhouse-ttl-tool rollback --list
+
+  # 预览回滚 SQL
+  clickhouse-ttl-tool rollback --run 20240101-120000-a1b2c3 --dry-run
+
+  # 执行回滚
+  clickhouse-ttl-tool rollback --run 20240101-120000-a1b2c3`,
+	RunE: runRollback,
+}
+
+func init() {
+	rollbackCmd.Flags().StringVar(&rollbackRunID, "run", "",
+		"要回滚的运行 ID（执行结束时输出）")
+
+	rollbackCmd.Flags().BoolVar(&rollbackList, "list", false,
+		"列出状态目录中可回滚的运行")
+
+	rollbackCmd.Flags().BoolVar(&rollbackForce, "force", false,
+		"表的 TTL 在运行之后被修改过时仍然回滚（覆盖之后的修改）")
+
+	rootCmd.AddCommand(rollbackCmd)
+}
+
+// runRollback 回滚执行函数
+func runRollback(cmd *cobra.Command, args []string) error {
+	printHeader()
+
+	store := state.NewStore(cfg.StateDir)
+
+	if rollbackList {
+		return listRuns(store)
+	}
+
+	if rollbackRunID == "" {
+		return errors.New("请通过 --run 指定运行 ID，或使用 --list 查看可回滚的运行")
+	}
+
+	if err := cfg.ValidateConnection(); err != nil {
+		return fmt.Errorf("配置验证失败: %w", err)
+	}
+
+	if err := checkInteractive(); err != nil {
+		return err
+	}
+
+	run, err := store.Load(rollbackRunID)
+	if err != nil {
+		return fmt.Errorf("加载运行状态失败: %w", err)
+	}
+
+	fmt.Fprintf(progressOut, "\n运行 %s（开始于 %s）共修改 %d 个表\n",
+		run.ID, run.StartedAt.Format("2006-01-02 15:04:05"), len(run.Entries))
+	if len(run.Entries) == 0 {
+		fmt.Fprintln(progressOut, "\n⚠ 该运行没有修改任何表，无需回滚")
+		return nil
+	}
+
+	cli, err := connect()
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	ctx := context.Background()
+
+	// 使用运行时的集群，保证回滚覆盖同样的节点
+	if cfg.Cluster != "" && cfg.Cluster != run.Cluster {
+		fmt.Fprintf(progressOut, "⚠ 忽略 --cluster %s，使用运行记录中的集群 %q\n", cfg.Cluster, run.Cluster)
+	}
+	tracker, err := newTracker(ctx, cli, run.Cluster)
+	if err != nil {
+		return err
+	}
+
+	if cfg.DryRun {
+		fmt.Fprintln(progressOut, "\n⚠️  预览模式：将显示回滚 SQL 但不实际执行")
+	} else {
+		fmt.Fprintln(progressOut, "\n"+strings.Repeat("=", 60))
+		fmt.Fprintln(progressOut, "⚠️  回滚确认")
+		fmt.Fprintln(progressOut, strings.Repeat("=", 60))
+		fmt.Fprintf(progressOut, "\n将把 %d 个表的 TTL 恢复为运行 %s 之前的状态\n", len(run.Entries), run.ID)
+		fmt.Fprintf(progressOut, "\n请输入运行 ID '%s' 以确认操作: ", run.ID)
+
+		ok, err := confirm(run.ID)
+		if err != nil || !ok {
+			return err
+		}
+	}
+
+	rep := reporter.NewReporter(progressOut, cfg.Verbose, cfg.DryRun)
+	exec := executor.NewExecutor(cli, executor.Options{
+		DryRun:        cfg.DryRun,
+		Verbose:       cfg.Verbose,
+		Cluster:       tracker,
+		Throttle:      newThrottle(cli),
+		Mutations:     newWatcher(cli, tracker, rep),
+		NoMaterialize: cfg.NoMaterialize,
+	})
+
+	fmt.Fprint(progressOut, "\n开始回滚...\n\n")
+
+	// 按执行的逆序恢复，每个表回滚前读取当前 TTL，核对运行之后是否被修改过
+	scan := scanner.NewScanner(cli, scanner.Filter{}, nil)
+	total := len(run.Entries)
+	for i := total - 1; i >= 0; i-- {
+		entry := run.Entries[i]
+		var result executor.ExecutionResult
+		current, err := scan.Table(ctx, entry.Database, entry.Table)
+		if err != nil && entry.Applied() {
+			result = executor.ExecutionResult{Database: entry.Database, Table: entry.Table, Error: err}
+		} else {
+			result = exec.Rollback(ctx, entry, current, rollbackForce)
+		}
+		rep.AddResult(result)
+		rep.PrintProgress(total-i, total, result)
+	}
+
+	summary := rep.PrintSummary()
+	if err := exportReport(rep, summary); err != nil {
+		return err
+	}
+	if summary.Failed > 0 {
+		return errors.New("部分表回滚失败")
+	}
+
+	return nil
+}
+
+// listRuns 列出状态目录中的运行
+func listRuns(store *state.Store) error {
+	ids, err := store.List()
+	if err != nil {
+		return err
+	}
+
+	if len(ids) == 0 {
+		fmt.Fprintf(progressOut, "\n状态目录 %s 中没有运行记录\n", cfg.StateDir)
+		return nil
+	}
+
+	fmt.Fprintf(progressOut, "\n状态目录: %s\n\n", cfg.StateDir)
+	fmt.Fprintf(progressOut, "%-24s %-20s %-8s %s\n", "运行 ID", "开始时间", "表数", "集群")
+	for _, id := range ids {
+		run, err := store.Load(id)
+		if err != nil {
+			fmt.Fprintf(progressOut, "%-24s 读取失败: %v\n", id, err)
+			continue
+		}
+		fmt.Fprintf(progressOut, "%-24s %-20s %-8d %s\n",
+			run.ID, run.StartedAt.Format("2006-01-02 15:04:05"), len(run.Entries), run.Cluster)
+	}
+
+	return nil
+}
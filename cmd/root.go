@@ -6,16 +6,23 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 	"time"
 
 	"clickhouse-ttl-tool/pkg/client"
+	"clickhouse-ttl-tool/pkg/cluster"
 	"clickhouse-ttl-tool/pkg/config"
 	"clickhouse-ttl-tool/pkg/detector"
 	"clickhouse-ttl-tool/pkg/executor"
+	"clickhouse-ttl-tool/pkg/matcher"
+	"clickhouse-ttl-tool/pkg/mutation"
+	"clickhouse-ttl-tool/pkg/policy"
 	"clickhouse-ttl-tool/pkg/reporter"
 	"clickhouse-ttl-tool/pkg/scanner"
+	"clickhouse-ttl-tool/pkg/state"
+	"clickhouse-ttl-tool/pkg/throttle"
 
 	"github.com/spf13/cobra"
 )
@@ -23,6 +30,11 @@ import (
 var (
 	// 配置参数
 	cfg config.Config
+
+	// progressOut 进度、提示和总结的输出目标；结构化报告输出到标准输出时为标准错误
+	progressOut io.Writer = os.Stdout
+	// reportOut 未指定 --report-file 时结构化报告的输出目标
+	reportOut io.Writer = os.Stdout
 )
 
 // rootCmd 根命令
@@ -31,21 +43,45 @@ var rootCmd = &cobra.Command{
 	Short: "为 ClickHouse 数据库中的所有表设置 TTL",
 	Long: `ClickHouse TTL Tool - 批量设置数据保留策略
 
-此工具自动扫描指定 ClickHouse 数据库中的所有表，
-检测时间字段（timestamp/event_time/created_at），
-并为每个表设置统一的 TTL 数据保留策略。
+此工具自动扫描指定 ClickHouse 数据库（可指定多个或全部数据库）中的所有表，
+按候选列表检测时间字段（默认 timestamp/event_time/created_at/time），
+并为每个表设置 TTL 数据保留策略。
+
+通过 --policy 指定 YAML 策略文件，可按表名（精确/glob/正则）
+为不同的表设置不同的保留天数、时间字段和 TTL 动作；
+未指定策略文件时，所有表统一使用 --retention-days。
 
-支持 DateTime/DateTime64/UInt64(纳秒) 类型的时间字段。`,
+支持 DateTime/DateTime64 类型和秒/毫秒/微秒/纳秒精度的整数时间戳。`,
 	Example: `  # 预览模式（不实际执行）
   clickhouse-ttl-tool --host localhost --database my_db --retention-days 30 --dry-run
 
   # 实际执行
   clickhouse-ttl-tool --host localhost --database my_db --retention-days 30
 
+  # 使用策略文件按表设置保留天数
+  clickhouse-ttl-tool --host localhost --database my_db --policy policy.yaml --dry-run
+
+  # 仅处理 log_ 开头的表，排除归档表
+  clickhouse-ttl-tool --host localhost --database my_db --retention-days 30 \
+    --include 'log_*' --exclude 're:_archive$'
+
+  # 处理多个数据库，或按模式匹配数据库
+  clickhouse-ttl-tool --host localhost --database db1,db2 --database 'logs_*' --retention-days 30 --dry-run
+
+  # 处理所有非系统数据库
+  clickhouse-ttl-tool --host localhost --all-databases --policy policy.yaml --dry-run
+
+  # 分片/副本集群：通过 ON CLUSTER 在所有节点设置
+  clickhouse-ttl-tool --host localhost --database my_db --retention-days 30 --cluster my_cluster
+
+  # 在 cron/CI 中非交互执行
+  clickhouse-ttl-tool --host localhost --database my_db --retention-days 30 --confirm my_db
+
   # 使用环境变量配置密码
   export CH_PASSWORD="secret"
   clickhouse-ttl-tool --host localhost --database my_db --retention-days 30`,
-	RunE: run,
+	PersistentPreRunE: redirectProgress,
+	RunE:              run,
 }
 
 // Execute 执行命令
@@ -54,192 +90,799 @@ func Execute() error {
 }
 
 func init() {
-	// 连接参数
-	rootCmd.Flags().StringVar(&cfg.Host, "host",
+	// 连接参数（所有子命令共用）
+	rootCmd.PersistentFlags().StringVar(&cfg.Host, "host",
 		config.GetEnvOrDefault("CH_HOST", "localhost"),
 		"ClickHouse 服务器地址 (环境变量: CH_HOST)")
 
-	rootCmd.Flags().IntVar(&cfg.Port, "port",
+	rootCmd.PersistentFlags().IntVar(&cfg.Port, "port",
 		9000,
 		"ClickHouse Native 协议端口 (环境变量: CH_PORT)")
 
-	rootCmd.Flags().StringVar(&cfg.User, "user",
+	rootCmd.PersistentFlags().StringVar(&cfg.User, "user",
 		config.GetEnvOrDefault("CH_USER", "default"),
 		"ClickHouse 用户名 (环境变量: CH_USER)")
 
-	rootCmd.Flags().StringVar(&cfg.Password, "password",
+	rootCmd.PersistentFlags().StringVar(&cfg.Password, "password",
 		os.Getenv("CH_PASSWORD"),
 		"ClickHouse 密码 (环境变量: CH_PASSWORD，推荐使用环境变量)")
 
-	// 必填参数
-	rootCmd.Flags().StringVar(&cfg.Database, "database", "",
-		"目标数据库名 (必填)")
-	rootCmd.MarkFlagRequired("database")
+	// 扫描和策略参数
+	addScanFlags(rootCmd)
 
-	rootCmd.Flags().IntVar(&cfg.RetentionDays, "retention-days", 0,
-		"数据保留天数 (必填)")
-	rootCmd.MarkFlagRequired("retention-days")
+	rootCmd.PersistentFlags().StringVar(&cfg.Cluster, "cluster", "",
+		"集群名，生成 ON CLUSTER 语句并跟踪各节点执行状态")
 
-	// 可选参数
-	rootCmd.Flags().BoolVar(&cfg.DryRun, "dry-run", false,
+	rootCmd.PersistentFlags().DurationVar(&cfg.DDLTimeout, "ddl-timeout", 180*time.Second,
+		"等待 ON CLUSTER 语句在各节点完成的超时时间")
+
+	rootCmd.PersistentFlags().IntVar(&cfg.Concurrency, "concurrency", 1,
+		"并发处理的表数（检测时间字段和执行 ALTER）")
+
+	// 限流参数：执行 ALTER 前服务端负载超过任一阈值时暂停，相邻两次 ALTER 之间保持固定间隔
+	rootCmd.PersistentFlags().DurationVar(&cfg.ThrottlePause, "throttle-pause", 100*time.Millisecond,
+		"相邻两次 ALTER 的最小间隔（并发时共享），0 表示不停顿")
+
+	rootCmd.PersistentFlags().IntVar(&cfg.ThrottleMaxMerges, "throttle-max-merges", 0,
+		"正在进行的合并数（system.merges）达到该值时暂停，0 表示不检查")
+
+	rootCmd.PersistentFlags().IntVar(&cfg.ThrottleMaxMutations, "throttle-max-mutations", 8,
+		"未完成的 mutation 数（system.mutations，不含反复失败的 mutation）达到该值时暂停，0 表示不检查")
+
+	rootCmd.PersistentFlags().IntVar(&cfg.ThrottleMaxParts, "throttle-max-parts", 300,
+		"单个分区的 part 数（MaxPartCountForPartition）达到该值时暂停，0 表示不检查")
+
+	rootCmd.PersistentFlags().Float64Var(&cfg.ThrottleMaxPoolUsage, "throttle-max-pool-usage", 0.9,
+		"合并/mutation 后台线程池使用率（0~1）达到该值时暂停，0 表示不检查")
+
+	rootCmd.PersistentFlags().DurationVar(&cfg.ThrottleInterval, "throttle-interval", 10*time.Second,
+		"负载超过阈值时重新检查的间隔")
+
+	rootCmd.PersistentFlags().DurationVar(&cfg.ThrottleMaxWait, "throttle-max-wait", 30*time.Minute,
+		"单个表最长等待时间，超过后该表执行失败")
+
+	rootCmd.PersistentFlags().BoolVar(&cfg.NoMaterialize, "no-materialize", false,
+		"修改 TTL 时不物化已有数据（materialize_ttl_after_modify=0），之后可用 materialize 子命令按分区物化")
+
+	rootCmd.PersistentFlags().BoolVar(&cfg.Wait, "wait", false,
+		"等待 ALTER 产生的 mutation 完成（system.mutations），mutation 失败时该表执行失败")
+
+	rootCmd.PersistentFlags().DurationVar(&cfg.WaitTimeout, "wait-timeout", time.Hour,
+		"等待单个表的 mutation 完成的超时时间")
+
+	rootCmd.PersistentFlags().DurationVar(&cfg.WaitLookupTimeout, "wait-lookup-timeout", 10*time.Second,
+		"复制表或 ON CLUSTER 时等待 mutation 出现在 system.mutations 中的最长时间，超过后视为没有 mutation")
+
+	rootCmd.PersistentFlags().StringVar(&cfg.StateDir, "state-dir", state.DefaultDir(),
+		"运行状态目录，记录修改前的 TTL 供 rollback 使用")
+
+	rootCmd.PersistentFlags().StringVar(&cfg.Output, "output", "text",
+		"报告格式: text / json / csv")
+
+	rootCmd.PersistentFlags().StringVar(&cfg.ReportFile, "report-file", "",
+		"报告输出文件，为空时输出到标准输出（json/csv 格式时进度信息改为输出到标准错误）")
+
+	rootCmd.PersistentFlags().BoolVarP(&cfg.Yes, "yes", "y", false,
+		"跳过交互确认，直接执行（用于 cron/CI）")
+
+	rootCmd.PersistentFlags().StringVar(&cfg.Confirm, "confirm", "",
+		"以非交互方式提供确认内容（单库为数据库名，多库为 yes，rollback 为运行 ID），不一致时拒绝执行")
+
+	rootCmd.PersistentFlags().BoolVar(&cfg.DryRun, "dry-run", false,
 		"预览模式，仅显示将要执行的 SQL，不实际执行")
 
-	rootCmd.Flags().BoolVar(&cfg.Verbose, "verbose", false,
+	rootCmd.PersistentFlags().BoolVar(&cfg.Verbose, "verbose", false,
 		"详细输出，显示每个表的 SQL 语句")
 }
 
+// session 一次扫描得到的执行上下文
+type session struct {
+	cli        *client.Client
+	tracker    *cluster.Tracker
+	pol        *policy.Policy
+	scn        *scanner.Scanner
+	candidates detector.Candidates
+	databases  []string
+	tables     []scanner.TableInfo
+}
+
+// Close 关闭连接
+func (s *session) Close() {
+	s.cli.Close()
+}
+
+// addScanFlags 注册扫描和策略相关参数（根命令与 plan 子命令共用）
+func addScanFlags(cmd *cobra.Command) {
+	addTargetFlags(cmd)
+
+	cmd.Flags().IntVar(&cfg.RetentionDays, "retention-days", 0,
+		"数据保留天数 (未指定 --policy 时必填；指定时作为策略的默认规则)")
+
+	cmd.Flags().StringVar(&cfg.PolicyFile, "policy", "",
+		"按表定义保留策略的 YAML 文件")
+
+	cmd.Flags().StringArrayVar(&cfg.TimeCandidates, "time-candidates", nil,
+		"时间字段候选，按优先级排列，支持 glob 或 re: 前缀的正则，可重复指定（覆盖策略文件的 time_candidates）")
+}
+
+// addTargetFlags 注册目标数据库和表过滤参数（remove 子命令仅使用这部分）
+func addTargetFlags(cmd *cobra.Command) {
+	// 目标数据库（--database 与 --all-databases 二选一）
+	cmd.Flags().StringArrayVar(&cfg.Databases, "database", nil,
+		"目标数据库名或模式，支持逗号分隔和重复指定，支持 glob 或 re: 前缀的正则（正则不按逗号拆分）")
+
+	cmd.Flags().BoolVar(&cfg.AllDatabases, "all-databases", false,
+		"处理所有非系统数据库")
+
+	// 可选参数
+	cmd.Flags().StringArrayVar(&cfg.Include, "include", nil,
+		"仅处理匹配的表，支持 glob 或 re: 前缀的正则，可重复指定")
+
+	cmd.Flags().StringArrayVar(&cfg.Exclude, "exclude", nil,
+		"排除匹配的表，支持 glob 或 re: 前缀的正则，可重复指定，优先于 --include")
+}
+
 // run 主执行函数
 func run(cmd *cobra.Command, args []string) error {
 	// 打印工具信息
 	printHeader()
 
+	// 无法确认时尽早失败，避免扫描后才发现
+	if err := checkInteractive(); err != nil {
+		return err
+	}
+
+	// 创建上下文
+	ctx := context.Background()
+
+	sess, err := openSession(ctx)
+	if err != nil || sess == nil {
+		return err
+	}
+	defer sess.Close()
+
+	// Dry-Run 模式提示
+	if cfg.DryRun {
+		fmt.Fprintln(progressOut, "\n⚠️  预览模式：将显示 SQL 语句但不实际执行")
+	} else {
+		// 非 Dry-Run 模式，需要用户确认
+		fmt.Fprintln(progressOut, "\n"+strings.Repeat("=", 60))
+		fmt.Fprintln(progressOut, "⚠️  危险操作警告")
+		fmt.Fprintln(progressOut, strings.Repeat("=", 60))
+		fmt.Fprintf(progressOut, "\n将要执行的操作:\n")
+		fmt.Fprintf(progressOut, "  • 数据库: %s\n", strings.Join(sess.databases, ", "))
+		fmt.Fprintf(progressOut, "  • 影响表数: %d 个\n", len(sess.tables))
+		if sess.tracker != nil {
+			fmt.Fprintf(progressOut, "  • 集群: %s (%d 个节点)\n", sess.tracker.Name(), len(sess.tracker.Hosts()))
+		}
+		fmt.Fprintf(progressOut, "  • 保留策略: %s\n", describePolicy(sess.pol))
+		fmt.Fprintf(progressOut, "  • 操作类型: 设置 TTL（数据超过保留天数将被自动删除）\n\n")
+		fmt.Fprintln(progressOut, "⚠️  注意: 此操作将覆盖已有的 TTL 设置（TTL 未变化的表会被跳过），且数据删除不可逆！")
+		token := confirmToken(sess.databases)
+		if len(sess.databases) == 1 {
+			fmt.Fprintf(progressOut, "\n请输入数据库名 '%s' 以确认操作: ", token)
+		} else {
+			fmt.Fprintf(progressOut, "\n将影响 %d 个数据库，请输入 '%s' 以确认操作: ", len(sess.databases), token)
+		}
+
+		ok, err := confirm(token)
+		if err != nil || !ok {
+			return err
+		}
+	}
+
+	// 实际执行时记录修改前的 TTL，供 rollback 恢复
+	var recorder *state.Recorder
+	if !cfg.DryRun {
+		recorder = state.NewRecorder(state.NewStore(cfg.StateDir), state.NewRunID(), cfg.Cluster)
+	}
+
+	// 创建执行器和报告器
+	rep := reporter.NewReporter(progressOut, cfg.Verbose, cfg.DryRun)
+	exec := executor.NewExecutor(sess.cli, executor.Options{
+		DryRun:        cfg.DryRun,
+		Verbose:       cfg.Verbose,
+		Cluster:       sess.tracker,
+		Recorder:      recorder,
+		Throttle:      newThrottle(sess.cli),
+		Mutations:     newWatcher(sess.cli, sess.tracker, rep),
+		NoMaterialize: cfg.NoMaterialize,
+	})
+
+	// 执行主流程
+	fmt.Fprint(progressOut, "\n开始处理...\n\n")
+	processTables(ctx, sess, exec, rep)
+
+	// 打印执行总结
+	summary := rep.PrintSummary()
+	printRollbackHint(recorder)
+	if err := exportReport(rep, summary); err != nil {
+		return err
+	}
+
+	// 根据结果返回退出码
+	if summary.Failed > 0 {
+		return errors.New("部分表执行失败")
+	}
+
+	if cfg.DryRun {
+		fmt.Fprintln(progressOut, "\n提示：去掉 --dry-run 参数以实际执行")
+	}
+
+	return nil
+}
+
+// openSession 验证配置、加载保留策略、连接 ClickHouse 并扫描表
+// 没有需要处理的数据库或表时返回 nil
+func openSession(ctx context.Context) (*session, error) {
 	// 验证配置
 	if err := cfg.Validate(); err != nil {
-		return fmt.Errorf("配置验证失败: %w", err)
+		return nil, fmt.Errorf("配置验证失败: %w", err)
+	}
+
+	// 加载保留策略
+	pol, err := loadPolicy()
+	if err != nil {
+		return nil, fmt.Errorf("加载策略失败: %w", err)
+	}
+
+	return scanTargets(ctx, pol)
+}
+
+// scanTargets 连接 ClickHouse 并按 --database/--include/--exclude 扫描表
+// pol 为 nil 表示不涉及保留策略（如 remove 子命令）；没有需要处理的数据库或表时返回 nil
+func scanTargets(ctx context.Context, pol *policy.Policy) (*session, error) {
+	// 解析表过滤条件
+	filter, err := buildFilter()
+	if err != nil {
+		return nil, fmt.Errorf("解析表过滤条件失败: %w", err)
+	}
+
+	// 解析时间字段候选
+	candidates, err := timeCandidates(pol)
+	if err != nil {
+		return nil, fmt.Errorf("解析时间字段候选失败: %w", err)
 	}
 
 	// 打印配置信息
-	printConfig()
+	printConfig(pol, candidates)
 
 	// 创建 ClickHouse 客户端
-	fmt.Println("\n正在连接 ClickHouse...")
-	cli, err := client.NewClient(&cfg)
+	cli, err := connect()
 	if err != nil {
-		return fmt.Errorf("连接失败: %w", err)
+		return nil, err
 	}
-	defer cli.Close()
-	fmt.Println("✓ 连接成功")
+	sess := &session{cli: cli, pol: pol, candidates: candidates}
 
-	// 创建上下文
-	ctx := context.Background()
+	// 校验集群名
+	sess.tracker, err = newTracker(ctx, cli, cfg.Cluster)
+	if err != nil {
+		cli.Close()
+		return nil, err
+	}
+
+	// 解析目标数据库
+	sess.scn = scanner.NewScanner(cli, filter, candidates).WithOutput(progressOut)
+	sess.databases, err = resolveDatabases(ctx, sess.scn)
+	if err != nil {
+		cli.Close()
+		return nil, fmt.Errorf("解析数据库失败: %w", err)
+	}
+	if len(sess.databases) == 0 {
+		cli.Close()
+		fmt.Fprintln(progressOut, "\n⚠ 没有匹配的数据库，无需操作")
+		return nil, nil
+	}
 
 	// 扫描表
-	fmt.Println("\n正在扫描数据库表...")
-	scn := scanner.NewScanner(cli)
-	tables, err := scn.ScanTables(ctx, cfg.Database)
+	fmt.Fprintf(progressOut, "\n正在扫描 %d 个数据库的表...\n", len(sess.databases))
+	sess.tables, err = sess.scn.ScanDatabases(ctx, sess.databases)
 	if err != nil {
-		return fmt.Errorf("扫描表失败: %w", err)
+		cli.Close()
+		return nil, fmt.Errorf("扫描表失败: %w", err)
 	}
-	fmt.Printf("✓ 找到 %d 个表\n", len(tables))
+	fmt.Fprintf(progressOut, "✓ 找到 %d 个表\n", len(sess.tables))
 
-	if len(tables) == 0 {
-		fmt.Println("\n⚠ 数据库中没有表，无需操作")
-		return nil
+	if len(sess.tables) == 0 {
+		cli.Close()
+		fmt.Fprintln(progressOut, "\n⚠ 数据库中没有表，无需操作")
+		return nil, nil
 	}
 
 	// 显示表和时间列信息
-	printTablesSummary(tables)
+	printTablesSummary(sess.tables, len(sess.databases) > 1)
 
-	// Dry-Run 模式提示
-	if cfg.DryRun {
-		fmt.Println("\n⚠️  预览模式：将显示 SQL 语句但不实际执行")
-	} else {
-		// 非 Dry-Run 模式，需要用户确认
-		fmt.Println("\n" + strings.Repeat("=", 60))
-		fmt.Println("⚠️  危险操作警告")
-		fmt.Println(strings.Repeat("=", 60))
-		fmt.Printf("\n将要执行的操作:\n")
-		fmt.Printf("  • 数据库: %s\n", cfg.Database)
-		fmt.Printf("  • 影响表数: %d 个\n", len(tables))
-		fmt.Printf("  • 保留天数: %d 天\n", cfg.RetentionDays)
-		fmt.Printf("  • 操作类型: 设置 TTL（数据超过 %d 天将被自动删除）\n\n", cfg.RetentionDays)
-		fmt.Println("⚠️  注意: 此操作将覆盖已有的 TTL 设置，且数据删除不可逆！")
-		fmt.Printf("\n请输入数据库名 '%s' 以确认操作: ", cfg.Database)
-
-		var confirm string
-		fmt.Scanln(&confirm)
-
-		if confirm != cfg.Database {
-			fmt.Println("\n✗ 确认失败，操作已取消")
-			return nil
+	return sess, nil
+}
+
+// processTables 按策略为每个表检测时间字段并执行 TTL 设置，结果写入报告器
+func processTables(ctx context.Context, sess *session, exec *executor.Executor, rep *reporter.Reporter) {
+	proc := &tableProcessor{
+		sess: sess,
+		det:  detector.NewDetector(sess.cli, sess.candidates),
+		exec: exec,
+	}
+	tables := sess.tables
+
+	// 策略包含移动子句时，加载存储策略用于校验目标磁盘和卷
+	if sess.pol.HasMoves() {
+		var err error
+		proc.storagePolicies, err = sess.scn.StoragePolicies(ctx)
+		if err != nil {
+			fmt.Fprintf(progressOut, "警告: %v，包含 TO DISK/TO VOLUME 的规则将跳过\n", err)
 		}
-		fmt.Println("\n✓ 确认成功，开始执行...")
 	}
 
-	// 创建检测器、执行器和报告器
-	det := detector.NewDetector(cli)
-	exec := executor.NewExecutor(cli, cfg.DryRun, cfg.Verbose)
-	rep := reporter.NewReporter(cfg.Verbose, cfg.DryRun)
+	forEachOrdered(len(tables), cfg.Concurrency,
+		func(i int) executor.ExecutionResult {
+			return proc.process(ctx, tables[i])
+		},
+		func(i int, result executor.ExecutionResult) {
+			rep.AddResult(result)
+			rep.PrintProgress(i+1, len(tables), result)
+		})
+}
 
-	// 执行主流程
-	fmt.Println("\n开始处理...\n")
+// tableProcessor 处理单个表所需的上下文
+type tableProcessor struct {
+	sess            *session
+	det             *detector.Detector
+	exec            *executor.Executor
+	storagePolicies map[string]*scanner.StoragePolicy
+}
+
+// process 为单个表匹配策略、检测时间字段、校验规则并执行 TTL 设置
+func (p *tableProcessor) process(ctx context.Context, table scanner.TableInfo) executor.ExecutionResult {
+	// 匹配策略规则
+	rule := matchRule(p.sess.pol, table)
+	if rule == nil {
+		return executor.SkippedResult(table, "未匹配任何策略规则")
+	}
+
+	skip := func(reason string) executor.ExecutionResult {
+		result := executor.SkippedResult(table, reason)
+		result.Rule = rule.String()
+		return result
+	}
 
-	for i, table := range tables {
-		// 检测时间字段，优先使用 Scanner 找到的时间列
-		timeCol, err := det.DetectTimeColumn(ctx, table.Database, table.Table, table.TimeColumns...)
+	if rule.Action == policy.ActionSkip {
+		return skip(fmt.Sprintf("策略规则 %s 要求跳过", rule))
+	}
+
+	// 检测时间字段：规则指定了时间字段时仅检测该字段，指定了候选时按规则的候选排序，
+	// 否则优先使用 Scanner 按全局候选找到的时间列；自动检测时参与分区键、排序键的列优先
+	keys := detector.Keys{Partition: table.PartitionKey, Sorting: table.SortingKey}
+	candidates := table.TimeColumns
+	if rule.TimeColumn != "" {
+		candidates = []string{rule.TimeColumn}
+	} else if patterns := rule.CandidatePatterns(); len(patterns) > 0 {
+		var err error
+		candidates, err = p.det.RankColumns(ctx, table.Database, table.Table, detector.Candidates(patterns))
 		if err != nil {
-			// 无时间字段，跳过
-			skipReason := "未找到合适的时间字段"
-			if len(table.TimeColumns) > 0 {
-				skipReason = fmt.Sprintf("时间列 [%s] 验证失败", strings.Join(table.TimeColumns, ", "))
-			}
-			result := executor.ExecutionResult{
-				Database:   table.Database,
-				Table:      table.Table,
-				Skipped:    true,
-				SkipReason: skipReason,
-			}
-			rep.AddResult(result)
-			rep.PrintProgress(i+1, len(tables), result)
+			return skip(fmt.Sprintf("查询表字段失败: %v", err))
+		}
+	}
+	if rule.TimeColumn == "" {
+		candidates = keys.Order(candidates)
+	}
+	det := p.det
+	if rule.TimeFormat != "" {
+		det = det.WithTimeFormat(rule.TimeFormat)
+	}
+	timeCol, err := det.DetectTimeColumn(ctx, table.Database, table.Table, candidates...)
+	if err != nil {
+		// 无时间字段，跳过
+		if len(candidates) > 0 {
+			return skip(fmt.Sprintf("时间列 [%s] 验证失败", strings.Join(candidates, ", ")))
+		}
+		return skip("未找到合适的时间字段")
+	}
+	if rule.TimeColumn != "" {
+		timeCol.Reason = fmt.Sprintf("策略规则 %s 指定", rule)
+	} else {
+		timeCol.Reason = keys.Reason(timeCol.Name)
+	}
+	timeCol.ExpireInvalid = rule.ExpireInvalidTime
+
+	// 引用其他列的规则（WHERE/GROUP BY/SET）和列级 TTL 需要表的字段信息
+	var columns []detector.Column
+	if rule.ReferencesColumns() || len(rule.ColumnTTL) > 0 {
+		columns, err = p.det.ListColumns(ctx, table.Database, table.Table)
+		if err != nil {
+			return skip(fmt.Sprintf("查询表字段失败: %v", err))
+		}
+	}
+
+	// 执行前校验 TTL 子句引用的列是否存在
+	if rule.ReferencesColumns() {
+		types := make(map[string]string, len(columns))
+		for _, c := range columns {
+			types[c.Name] = c.Type
+		}
+		if missing := rule.MissingColumns(types); len(missing) > 0 {
+			return skip(fmt.Sprintf("TTL 子句引用了不存在的列 [%s]", strings.Join(missing, ", ")))
+		}
+	}
+
+	// 带聚合子句的规则：GROUP BY 键必须是表排序键的前缀，否则 ALTER 会失败
+	if rule.HasGroupBy() {
+		if skipReason := checkGroupBy(table, rule); skipReason != "" {
+			return skip(skipReason)
+		}
+	}
+
+	// 带移动子句的规则：校验目标磁盘和卷属于表的存储策略
+	if rule.HasMoves() {
+		if skipReason := checkMoveTargets(p.storagePolicies, table, rule); skipReason != "" {
+			return skip(skipReason)
+		}
+	}
+
+	// 列级 TTL：按列名模式匹配
+	columnRules := matchColumnRules(rule, columns, timeCol)
+	ttlRules := rule.TTLRules()
+	if len(ttlRules) == 0 && len(columnRules) == 0 {
+		return skip("没有可设置列级 TTL 的列（主键、排序键、分区键和时间字段除外）")
+	}
+
+	// 执行 TTL 设置
+	result := p.exec.Execute(ctx, table, timeCol, ttlRules, columnRules)
+	result.Rule = rule.String()
+	return result
+}
+
+// matchColumnRules 返回需要设置列级 TTL 的列
+// 主键、排序键和分区键列不能设置列级 TTL（服务端报 Trying to set TTL for key column），时间字段本身也不设置
+func matchColumnRules(rule *policy.Rule, columns []detector.Column, timeCol *detector.TimeColumn) []executor.ColumnRule {
+	var rules []executor.ColumnRule
+	for _, c := range columns {
+		if c.InPrimaryKey || c.InSortingKey || c.InPartitionKey || c.Name == timeCol.Name {
 			continue
 		}
+		if days := rule.ColumnRetention(c.Name); days > 0 {
+			rules = append(rules, executor.ColumnRule{Column: c.Name, RetentionDays: days})
+		}
+	}
+	return rules
+}
+
+// checkGroupBy 校验规则中 GROUP BY 键是否为表排序键的前缀
+// 校验不通过时返回跳过原因，通过时返回空
+func checkGroupBy(table scanner.TableInfo, rule *policy.Rule) string {
+	for _, t := range rule.TTLRules() {
+		if t.IsGroupBy() && !table.HasSortingKeyPrefix(t.GroupBy) {
+			return fmt.Sprintf("GROUP BY (%s) 不是排序键 (%s) 的前缀",
+				strings.Join(t.GroupBy, ", "), table.SortingKey)
+		}
+	}
+	return ""
+}
 
-		// 执行 TTL 设置
-		result := exec.Execute(ctx, table, timeCol, cfg.RetentionDays)
-		rep.AddResult(result)
-		rep.PrintProgress(i+1, len(tables), result)
+// checkMoveTargets 校验规则中 TO DISK/TO VOLUME 引用的磁盘和卷是否属于表的存储策略
+// 校验不通过时返回跳过原因，通过时返回空
+func checkMoveTargets(policies map[string]*scanner.StoragePolicy, table scanner.TableInfo, rule *policy.Rule) string {
+	if policies == nil {
+		return "无法读取存储策略，不能校验 TO DISK/TO VOLUME"
+	}
+	sp, ok := policies[table.StoragePolicy]
+	if !ok {
+		return fmt.Sprintf("未找到表的存储策略 %q", table.StoragePolicy)
+	}
 
-		// 添加短暂延迟，避免对 ClickHouse 造成过大压力
-		if !cfg.DryRun && i < len(tables)-1 {
-			time.Sleep(100 * time.Millisecond)
+	var missing []string
+	for _, t := range rule.TTLRules() {
+		if t.ToDisk != "" && !sp.HasDisk(t.ToDisk) {
+			missing = append(missing, "磁盘 "+t.ToDisk)
 		}
+		if t.ToVolume != "" && !sp.HasVolume(t.ToVolume) {
+			missing = append(missing, "卷 "+t.ToVolume)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Sprintf("存储策略 %s 不包含%s", sp.Name, strings.Join(missing, "、"))
 	}
+	return ""
+}
 
-	// 打印执行总结
-	summary := rep.PrintSummary()
+// redirectProgress 校验报告格式；JSON/CSV 报告输出到标准输出时，进度和总结等信息改为输出到标准错误，
+// 保证标准输出只包含可以直接解析的报告
+func redirectProgress(cmd *cobra.Command, args []string) error {
+	format, err := reporter.ParseFormat(cfg.Output)
+	if err != nil {
+		return err
+	}
+	reportOut = cmd.OutOrStdout()
+	progressOut = reportOut
+	if format != reporter.FormatText && cfg.ReportFile == "" {
+		progressOut = cmd.ErrOrStderr()
+	}
+	return nil
+}
 
-	// 根据结果返回退出码
-	if summary.Failed > 0 {
-		return errors.New("部分表执行失败")
+// exportReport 按 --output/--report-file 输出结构化报告
+// 文本格式且未指定报告文件时，终端输出即为报告，不再重复输出
+func exportReport(rep *reporter.Reporter, summary reporter.Summary) error {
+	format, err := reporter.ParseFormat(cfg.Output)
+	if err != nil {
+		return err
+	}
+	if format == reporter.FormatText && cfg.ReportFile == "" {
+		return nil
 	}
 
-	if cfg.DryRun {
-		fmt.Println("\n提示：去掉 --dry-run 参数以实际执行")
+	if cfg.ReportFile == "" {
+		if err := rep.Write(reportOut, format, summary); err != nil {
+			return fmt.Errorf("输出报告失败: %w", err)
+		}
+		return nil
 	}
 
+	if err := rep.Export(format, cfg.ReportFile, summary); err != nil {
+		return fmt.Errorf("输出报告失败: %w", err)
+	}
+	fmt.Fprintf(progressOut, "\n✓ 报告已写入 %s (%s)\n", cfg.ReportFile, format)
 	return nil
 }
 
+// printRollbackHint 有修改记录时提示回滚方式
+func printRollbackHint(recorder *state.Recorder) {
+	if recorder == nil || recorder.Count() == 0 {
+		return
+	}
+	fmt.Fprintf(progressOut, "\n运行 ID: %s（修改前的 TTL 已保存到 %s）\n", recorder.RunID(), cfg.StateDir)
+	fmt.Fprintf(progressOut, "如需回滚: clickhouse-ttl-tool rollback --run %s\n", recorder.RunID())
+}
+
 // printHeader 打印工具头部信息
 func printHeader() {
-	fmt.Println(strings.Repeat("=", 60))
-	fmt.Println("ClickHouse TTL Tool v1.0.0")
-	fmt.Println(strings.Repeat("=", 60))
+	fmt.Fprintln(progressOut, strings.Repeat("=", 60))
+	fmt.Fprintln(progressOut, "ClickHouse TTL Tool v1.0.0")
+	fmt.Fprintln(progressOut, strings.Repeat("=", 60))
+}
+
+// loadPolicy 加载保留策略
+// 未指定策略文件时，所有表统一使用 --retention-days
+func loadPolicy() (*policy.Policy, error) {
+	if cfg.PolicyFile == "" {
+		return policy.FromRetention(cfg.RetentionDays), nil
+	}
+
+	pol, err := policy.Load(cfg.PolicyFile)
+	if err != nil {
+		return nil, err
+	}
+	pol.SetDefaultRetention(cfg.RetentionDays)
+	return pol, nil
+}
+
+// connect 创建 ClickHouse 客户端
+func connect() (*client.Client, error) {
+	fmt.Fprintln(progressOut, "\n正在连接 ClickHouse...")
+	cli, err := client.NewClient(&cfg)
+	if err != nil {
+		return nil, fmt.Errorf("连接失败: %w", err)
+	}
+	fmt.Fprintln(progressOut, "✓ 连接成功")
+	return cli, nil
+}
+
+// newTracker 校验集群名并创建分布式 DDL 跟踪器，未指定集群时返回 nil
+func newTracker(ctx context.Context, cli *client.Client, name string) (*cluster.Tracker, error) {
+	if name == "" {
+		return nil, nil
+	}
+
+	tracker, err := cluster.NewTracker(ctx, cli, name, cfg.DDLTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("校验集群失败: %w", err)
+	}
+	fmt.Fprintf(progressOut, "✓ 集群 %s 共 %d 个节点\n", tracker.Name(), len(tracker.Hosts()))
+	return tracker, nil
+}
+
+// newThrottle 根据限流参数创建限流器，所有阈值和间隔均为 0 时返回 nil
+func newThrottle(cli *client.Client) *throttle.Throttle {
+	th := throttle.Thresholds{
+		MaxMerges:    cfg.ThrottleMaxMerges,
+		MaxMutations: cfg.ThrottleMaxMutations,
+		MaxParts:     cfg.ThrottleMaxParts,
+		MaxPoolUsage: cfg.ThrottleMaxPoolUsage,
+	}
+	if th == (throttle.Thresholds{}) && cfg.ThrottlePause == 0 {
+		return nil
+	}
+
+	return throttle.New(cli, throttle.Options{
+		Thresholds: th,
+		Interval:   cfg.ThrottleInterval,
+		MaxWait:    cfg.ThrottleMaxWait,
+		Pause:      cfg.ThrottlePause,
+	})
+}
+
+// newWatcher 创建 mutation 跟踪器，未指定 --wait 时返回 nil
+// 等待过程中的进度通过报告器输出
+func newWatcher(cli *client.Client, tracker *cluster.Tracker, rep *reporter.Reporter) *mutation.Watcher {
+	if !cfg.Wait {
+		return nil
+	}
+
+	name := ""
+	if tracker != nil {
+		name = tracker.Name()
+	}
+	return mutation.NewWatcher(cli, name, cfg.WaitTimeout, cfg.WaitLookupTimeout, rep.PrintMutationProgress)
+}
+
+// confirm 确认危险操作，确认通过时返回 true
+// 优先使用 --yes/--confirm；都未指定时从终端读取，标准输入不是终端时直接报错
+func confirm(token string) (bool, error) {
+	if cfg.Yes {
+		fmt.Fprintln(progressOut, "\n✓ 已通过 --yes 确认，开始执行...")
+		return true, nil
+	}
+
+	if cfg.Confirm != "" {
+		if cfg.Confirm != token {
+			return false, fmt.Errorf("--confirm %q 与需要确认的内容 %q 不一致，操作已取消", cfg.Confirm, token)
+		}
+		fmt.Fprintln(progressOut, "\n✓ 已通过 --confirm 确认，开始执行...")
+		return true, nil
+	}
+
+	if err := checkInteractive(); err != nil {
+		return false, err
+	}
+
+	var input string
+	fmt.Scanln(&input)
+
+	if input != token {
+		fmt.Fprintln(progressOut, "\n✗ 确认失败，操作已取消")
+		return false, nil
+	}
+	fmt.Fprintln(progressOut, "\n✓ 确认成功，开始执行...")
+	return true, nil
+}
+
+// checkInteractive 非预览模式下检查能否完成确认
+// 未指定 --yes/--confirm 且标准输入不是终端（cron、CI 等）时返回错误，避免读到空输入后静默取消
+func checkInteractive() error {
+	if cfg.DryRun || cfg.Yes || cfg.Confirm != "" || isTerminal(os.Stdin) {
+		return nil
+	}
+	return errors.New("标准输入不是终端，无法交互确认，请使用 --yes 或 --confirm <确认内容> 以非交互方式运行")
+}
+
+// matchRule 为表匹配策略规则
+// 由分布式表解析得到的本地表，若本地表名只命中默认规则，则改用分布式表名匹配
+func matchRule(pol *policy.Policy, table scanner.TableInfo) *policy.Rule {
+	rule := pol.Match(table.Database, table.Table)
+	if table.Distributed == nil || (rule != nil && !rule.IsDefault()) {
+		return rule
+	}
+	if r := pol.Match(table.Distributed.Database, table.Distributed.Table); r != nil {
+		return r
+	}
+	return rule
+}
+
+// resolveDatabases 将 --database/--all-databases 解析为实际的数据库列表
+func resolveDatabases(ctx context.Context, scn *scanner.Scanner) ([]string, error) {
+	patterns, err := matcher.ParseAll(matcher.SplitList(cfg.Databases))
+	if err != nil {
+		return nil, err
+	}
+	return scn.ResolveDatabases(ctx, patterns, cfg.AllDatabases)
+}
+
+// confirmToken 返回确认操作时需要输入的内容
+// 单个数据库时输入数据库名，多个数据库时输入 yes
+func confirmToken(databases []string) string {
+	if len(databases) == 1 {
+		return databases[0]
+	}
+	return "yes"
+}
+
+// timeCandidates 解析时间字段候选
+// 优先级：--time-candidates > 策略文件的 time_candidates > 内置候选
+func timeCandidates(pol *policy.Policy) (detector.Candidates, error) {
+	if len(cfg.TimeCandidates) > 0 {
+		return detector.ParseCandidates(cfg.TimeCandidates)
+	}
+	if pol != nil && len(pol.CandidatePatterns()) > 0 {
+		return detector.Candidates(pol.CandidatePatterns()), nil
+	}
+	return detector.ParseCandidates(detector.DefaultCandidates)
+}
+
+// describeDatabases 返回目标数据库的简要描述
+func describeDatabases() string {
+	if cfg.AllDatabases {
+		return "全部（排除系统库）"
+	}
+	return strings.Join(cfg.Databases, ", ")
+}
+
+// buildFilter 根据 --include/--exclude 构建表过滤条件
+func buildFilter() (scanner.Filter, error) {
+	include, err := matcher.ParseAll(cfg.Include)
+	if err != nil {
+		return scanner.Filter{}, err
+	}
+
+	exclude, err := matcher.ParseAll(cfg.Exclude)
+	if err != nil {
+		return scanner.Filter{}, err
+	}
+
+	return scanner.Filter{Include: include, Exclude: exclude}, nil
+}
+
+// describePolicy 返回保留策略的简要描述
+func describePolicy(pol *policy.Policy) string {
+	if cfg.PolicyFile == "" {
+		return fmt.Sprintf("%d 天", cfg.RetentionDays)
+	}
+
+	desc := fmt.Sprintf("策略文件 %s (%d 条规则", cfg.PolicyFile, len(pol.Rules))
+	if pol.Default != nil && pol.Default.Action == policy.ActionDelete {
+		if len(pol.Default.TTL) > 0 {
+			desc += fmt.Sprintf("，默认 %d 条 TTL 子句", len(pol.Default.TTLRules()))
+		} else {
+			desc += fmt.Sprintf("，默认 %d 天", pol.Default.RetentionDays)
+		}
+	} else if pol.Default == nil {
+		desc += "，未匹配的表将跳过"
+	}
+	return desc + ")"
 }
 
 // printConfig 打印配置信息
-func printConfig() {
-	fmt.Println("\n配置信息:")
-	fmt.Printf("  连接地址: %s:%d\n", cfg.Host, cfg.Port)
-	fmt.Printf("  数据库: %s\n", cfg.Database)
-	fmt.Printf("  用户名: %s\n", cfg.User)
-	fmt.Printf("  保留天数: %d 天\n", cfg.RetentionDays)
+func printConfig(pol *policy.Policy, candidates detector.Candidates) {
+	fmt.Fprintln(progressOut, "\n配置信息:")
+	fmt.Fprintf(progressOut, "  连接地址: %s:%d\n", cfg.Host, cfg.Port)
+	fmt.Fprintf(progressOut, "  数据库: %s\n", describeDatabases())
+	fmt.Fprintf(progressOut, "  用户名: %s\n", cfg.User)
+	if cfg.Cluster != "" {
+		fmt.Fprintf(progressOut, "  集群: %s (ON CLUSTER)\n", cfg.Cluster)
+	}
+	if pol != nil {
+		fmt.Fprintf(progressOut, "  保留策略: %s\n", describePolicy(pol))
+	}
+	if len(cfg.Include) > 0 {
+		fmt.Fprintf(progressOut, "  包含表: %s\n", strings.Join(cfg.Include, ", "))
+	}
+	if len(cfg.Exclude) > 0 {
+		fmt.Fprintf(progressOut, "  排除表: %s\n", strings.Join(cfg.Exclude, ", "))
+	}
+	fmt.Fprintf(progressOut, "  时间字段候选: %s\n", strings.Join(candidates.Strings(), ", "))
+	if cfg.NoMaterialize {
+		fmt.Fprintf(progressOut, "  物化: 否 (materialize_ttl_after_modify=0)\n")
+	}
 	if cfg.DryRun {
-		fmt.Printf("  模式: 预览 (Dry-Run)\n")
+		fmt.Fprintf(progressOut, "  模式: 预览 (Dry-Run)\n")
 	} else {
-		fmt.Printf("  模式: 实际执行\n")
+		fmt.Fprintf(progressOut, "  模式: 实际执行\n")
 	}
 }
 
 // printTablesSummary 打印表及时间列摘要信息
-func printTablesSummary(tables []scanner.TableInfo) {
-	fmt.Println("\n表信息摘要:")
-	fmt.Println(strings.Repeat("-", 80))
-	fmt.Printf("%-40s %-20s %s\n", "表名", "引擎", "时间列")
-	fmt.Println(strings.Repeat("-", 80))
+// qualified 为 true 时以 database.table 形式显示表名
+func printTablesSummary(tables []scanner.TableInfo, qualified bool) {
+	fmt.Fprintln(progressOut, "\n表信息摘要:")
+	fmt.Fprintln(progressOut, strings.Repeat("-", 80))
+	fmt.Fprintf(progressOut, "%-40s %-20s %s\n", "表名", "引擎", "时间列")
+	fmt.Fprintln(progressOut, strings.Repeat("-", 80))
 
 	tablesWithTime := 0
+	tablesWithTTL := 0
 	for _, table := range tables {
+		if table.CurrentTTL != "" {
+			tablesWithTTL++
+		}
+
 		timeColsStr := "无"
 		if len(table.TimeColumns) > 0 {
 			timeColsStr = strings.Join(table.TimeColumns, ", ")
@@ -248,19 +891,30 @@ func printTablesSummary(tables []scanner.TableInfo) {
 
 		// 截断过长的表名
 		tableName := table.Table
-		if len(tableName) > 37 {
-			tableName = tableName[:34] + "..."
+		if qualified {
+			tableName = table.Database + "." + table.Table
+		}
+		if table.Distributed != nil {
+			tableName = table.Distributed.Table + " → " + tableName
 		}
+		tableName = truncate(tableName, 37)
 
 		// 截断过长的引擎名
-		engine := table.Engine
-		if len(engine) > 17 {
-			engine = engine[:14] + "..."
-		}
+		engine := truncate(table.Engine, 17)
 
-		fmt.Printf("%-40s %-20s %s\n", tableName, engine, timeColsStr)
+		fmt.Fprintf(progressOut, "%-40s %-20s %s\n", tableName, engine, timeColsStr)
 	}
 
-	fmt.Println(strings.Repeat("-", 80))
-	fmt.Printf("统计: 有时间列 %d 个 / 总计 %d 个表\n", tablesWithTime, len(tables))
+	fmt.Fprintln(progressOut, strings.Repeat("-", 80))
+	fmt.Fprintf(progressOut, "统计: 有时间列 %d 个 / 已有 TTL %d 个 / 总计 %d 个表\n", tablesWithTime, tablesWithTTL, len(tables))
+}
+
+// truncate 将超过 n 个字符的字符串截断并以 ... 结尾
+// 按 rune 计数，避免切断 → 或中文等多字节字符
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-3]) + "..."
 }
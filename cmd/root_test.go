@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"clickhouse-ttl-tool/pkg/policy"
+	"clickhouse-ttl-tool/pkg/scanner"
+
+	"github.com/spf13/cobra"
+)
+
+func TestRedirectProgress(t *testing.T) {
+	saved := cfg
+	savedProgress, savedReport := progressOut, reportOut
+	defer func() {
+		cfg = saved
+		progressOut, reportOut = savedProgress, savedReport
+	}()
+
+	tests := []struct {
+		name         string
+		output       string
+		reportFile   string
+		wantProgress string // stdout 或 stderr
+		wantErr      bool
+	}{
+		{name: "text", output: "text", wantProgress: "stdout"},
+		{name: "json to stdout", output: "json", wantProgress: "stderr"},
+		{name: "csv to stdout", output: "csv", wantProgress: "stderr"},
+		{name: "json to file", output: "json", reportFile: "report.json", wantProgress: "stdout"},
+		{name: "unknown format", output: "xml", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			cmd := &cobra.Command{}
+			cmd.SetOut(&stdout)
+			cmd.SetErr(&stderr)
+			cfg.Output, cfg.ReportFile = tt.output, tt.reportFile
+
+			err := redirectProgress(cmd, nil)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("redirectProgress() error = nil, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("redirectProgress() error = %v", err)
+			}
+
+			want := map[string]io.Writer{"stdout": &stdout, "stderr": &stderr}[tt.wantProgress]
+			if progressOut != want {
+				t.Errorf("progress goes to the wrong writer, want %s", tt.wantProgress)
+			}
+			if reportOut != io.Writer(&stdout) {
+				t.Errorf("report should go to stdout")
+			}
+		})
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{"events", 10, "events"},
+		{"events_local", 10, "events_..."},
+		{"events → db.events_local", 24, "events → db.events_local"},
+		{"events → db.events_local", 12, "events → ..."},
+		{"日志表_按天分区", 6, "日志表..."},
+	}
+
+	for _, tt := range tests {
+		if got := truncate(tt.s, tt.n); got != tt.want {
+			t.Errorf("truncate(%q, %d) = %q, want %q", tt.s, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestCheckMoveTargets(t *testing.T) {
+	policies := map[string]*scanner.StoragePolicy{
+		"hot_cold": {
+			Name:    "hot_cold",
+			Volumes: map[string][]string{"hot": {"ssd"}, "cold": {"hdd1", "hdd2"}},
+		},
+	}
+	table := scanner.TableInfo{Database: "logs", Table: "events", StoragePolicy: "hot_cold"}
+
+	tests := []struct {
+		name     string
+		policies map[string]*scanner.StoragePolicy
+		table    scanner.TableInfo
+		ttl      []policy.TTLRule
+		wantSkip bool
+	}{
+		{
+			name:     "disk and volume exist",
+			policies: policies,
+			table:    table,
+			ttl:      []policy.TTLRule{{RetentionDays: 7, ToDisk: "hdd2"}, {RetentionDays: 30, ToVolume: "cold"}},
+		},
+		{
+			name:     "missing disk",
+			policies: policies,
+			table:    table,
+			ttl:      []policy.TTLRule{{RetentionDays: 7, ToDisk: "s3"}},
+			wantSkip: true,
+		},
+		{
+			name:     "missing volume",
+			policies: policies,
+			table:    table,
+			ttl:      []policy.TTLRule{{RetentionDays: 7, ToVolume: "archive"}},
+			wantSkip: true,
+		},
+		{
+			name:     "unknown storage policy",
+			policies: policies,
+			table:    scanner.TableInfo{Database: "logs", Table: "events", StoragePolicy: "default"},
+			ttl:      []policy.TTLRule{{RetentionDays: 7, ToDisk: "ssd"}},
+			wantSkip: true,
+		},
+		{
+			name:     "storage policies unavailable",
+			table:    table,
+			ttl:      []policy.TTLRule{{RetentionDays: 7, ToDisk: "ssd"}},
+			wantSkip: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := &policy.Rule{RetentionDays: 90, TTL: tt.ttl}
+			reason := checkMoveTargets(tt.policies, tt.table, rule)
+			if (reason != "") != tt.wantSkip {
+				t.Errorf("checkMoveTargets() = %q, want skip %v", reason, tt.wantSkip)
+			}
+		})
+	}
+}
+
+func TestCheckGroupBy(t *testing.T) {
+	table := scanner.TableInfo{Database: "logs", Table: "metrics", SortingKey: "tenant_id, toStartOfDay(ts)"}
+
+	rule := &policy.Rule{TTL: []policy.TTLRule{{RetentionDays: 30, GroupBy: []string{"tenant_id"}}}}
+	if reason := checkGroupBy(table, rule); reason != "" {
+		t.Errorf("checkGroupBy() = %q, want pass", reason)
+	}
+
+	rule = &policy.Rule{TTL: []policy.TTLRule{{RetentionDays: 30, GroupBy: []string{"toStartOfDay(ts)"}}}}
+	if reason := checkGroupBy(table, rule); reason == "" {
+		t.Error("checkGroupBy() passed, want skip for a non-prefix key")
+	}
+}
@@ -0,0 +1,16 @@
+//go:build linux
+
+package cmd
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// isTerminal 判断文件是否为终端
+// 不能只看 ModeCharDevice：cron 等环境的标准输入通常是 /dev/null，它也是字符设备
+func isTerminal(f *os.File) bool {
+	_, err := unix.IoctlGetTermios(int(f.Fd()), unix.TCGETS)
+	return err == nil
+}
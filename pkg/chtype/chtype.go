@@ -0,0 +1,129 @@
+// 使用方法：解析 system.columns 中的 ClickHouse 类型字符串
+// 去掉 Nullable / LowCardinality 包装，解析 DateTime64(3, 'UTC') 等类型参数
+package chtype
+
+import "strings"
+
+// Type 解析后的列类型
+type Type struct {
+	Base           string   // 去掉包装后的类型名，如 DateTime64
+	Params         []string // 类型参数，如 DateTime64(3, 'UTC') 为 ["3", "'UTC'"]
+	Nullable       bool     // 是否为 Nullable
+	LowCardinality bool     // 是否为 LowCardinality
+}
+
+// integerTypes 可以保存 Unix 时间戳的整数类型
+var integerTypes = map[string]bool{
+	"UInt32": true,
+	"UInt64": true,
+	"Int32":  true,
+	"Int64":  true,
+}
+
+// Parse 解析类型字符串，逐层去掉 Nullable 和 LowCardinality 包装
+// 例如 LowCardinality(Nullable(DateTime('UTC'))) 解析为 Base=DateTime, Params=['UTC'], 两个包装均为 true
+func Parse(s string) Type {
+	var t Type
+	s = strings.TrimSpace(s)
+
+	for {
+		name, inner, ok := split(s)
+		if !ok {
+			t.Base = s
+			return t
+		}
+
+		switch name {
+		case "Nullable":
+			t.Nullable = true
+			s = strings.TrimSpace(inner)
+		case "LowCardinality":
+			t.LowCardinality = true
+			s = strings.TrimSpace(inner)
+		default:
+			t.Base = name
+			t.Params = splitParams(inner)
+			return t
+		}
+	}
+}
+
+// IsDateTime 判断是否为日期或日期时间类型（Date、Date32、DateTime、DateTime64）
+func (t Type) IsDateTime() bool {
+	switch t.Base {
+	case "Date", "Date32", "DateTime", "DateTime64":
+		return true
+	default:
+		return false
+	}
+}
+
+// IsDate32 判断是否为 Date32（TTL 中需要转换为 Date）
+func (t Type) IsDate32() bool {
+	return t.Base == "Date32"
+}
+
+// IsDateTime64 判断是否为 DateTime64（TTL 中需要转换为 DateTime）
+func (t Type) IsDateTime64() bool {
+	return t.Base == "DateTime64"
+}
+
+// IsInteger 判断是否为可以保存 Unix 时间戳的整数类型
+func (t Type) IsInteger() bool {
+	return integerTypes[t.Base]
+}
+
+// IsString 判断是否为 String（可能以文本形式保存时间）
+func (t Type) IsString() bool {
+	return t.Base == "String"
+}
+
+// split 将 Name(inner) 拆分为类型名和括号内的内容，没有参数时 ok 为 false
+func split(s string) (name, inner string, ok bool) {
+	open := strings.IndexByte(s, '(')
+	if open < 0 || !strings.HasSuffix(s, ")") {
+		return "", "", false
+	}
+	return strings.TrimSpace(s[:open]), s[open+1 : len(s)-1], true
+}
+
+// splitParams 按顶层逗号拆分类型参数，忽略括号和引号内的逗号
+func splitParams(s string) []string {
+	var (
+		params []string
+		start  int
+		depth  int
+		quote  byte
+	)
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if quote != 0 {
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		}
+
+		switch c {
+		case '\'', '`', '"':
+			quote = c
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				params = append(params, strings.TrimSpace(s[start:i]))
+				start = i + 1
+			}
+		}
+	}
+
+	if last := strings.TrimSpace(s[start:]); last != "" || len(params) > 0 {
+		params = append(params, last)
+	}
+	return params
+}
@@ -0,0 +1,54 @@
+package chtype
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Type
+	}{
+		{"DateTime", Type{Base: "DateTime"}},
+		{"Date32", Type{Base: "Date32"}},
+		{"DateTime('Asia/Shanghai')", Type{Base: "DateTime", Params: []string{"'Asia/Shanghai'"}}},
+		{"DateTime64(3, 'UTC')", Type{Base: "DateTime64", Params: []string{"3", "'UTC'"}}},
+		{"Nullable(UInt64)", Type{Base: "UInt64", Nullable: true}},
+		{"LowCardinality(String)", Type{Base: "String", LowCardinality: true}},
+		{
+			"LowCardinality(Nullable(DateTime('UTC')))",
+			Type{Base: "DateTime", Params: []string{"'UTC'"}, Nullable: true, LowCardinality: true},
+		},
+		{"Array(Nullable(DateTime))", Type{Base: "Array", Params: []string{"Nullable(DateTime)"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := Parse(tt.in); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitParams(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"", nil},
+		{"3", []string{"3"}},
+		{"3, 'UTC'", []string{"3", "'UTC'"}},
+		{"String, Array(Tuple(a UInt8, b String))", []string{"String", "Array(Tuple(a UInt8, b String))"}},
+		{"'a, b' = 1, 'c\\'d' = 2", []string{"'a, b' = 1", "'c\\'d' = 2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := splitParams(tt.in); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitParams(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
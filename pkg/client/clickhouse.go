@@ -17,15 +17,16 @@ import (
 // Client ClickHouse 客户端封装
 type Client struct {
 	conn driver.Conn
-	db   string
 }
 
 // NewClient 创建新的 ClickHouse 客户端连接
+// 连接不绑定具体数据库，所有查询均使用 database.table 形式的完整表名
+// 连接池大小随 cfg.Concurrency 扩大，保证每个并发任务都能拿到连接
 func NewClient(cfg *config.Config) (*Client, error) {
 	conn, err := clickhouse.Open(&clickhouse.Options{
-		Addr: []string{fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)},
+		MaxOpenConns: cfg.Concurrency + 5,
+		Addr:         []string{fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)},
 		Auth: clickhouse.Auth{
-			Database: cfg.Database,
 			Username: cfg.User,
 			Password: cfg.Password,
 		},
@@ -52,7 +53,6 @@ func NewClient(cfg *config.Config) (*Client, error) {
 
 	return &Client{
 		conn: conn,
-		db:   cfg.Database,
 	}, nil
 }
 
@@ -268,7 +268,9 @@ func (c *Client) Exec(ctx context.Context, query string, args ...interface{}) er
 	return nil
 }
 
-// GetDatabase 获取当前连接的数据库名
-func (c *Client) GetDatabase() string {
-	return c.db
+// ExecWithSettings 使用查询级别的设置执行语句
+// 例如 ON CLUSTER 语句使用 distributed_ddl_output_mode 控制是否等待各节点完成
+func (c *Client) ExecWithSettings(ctx context.Context, settings map[string]interface{}, query string, args ...interface{}) error {
+	ctx = clickhouse.Context(ctx, clickhouse.WithSettings(clickhouse.Settings(settings)))
+	return c.Exec(ctx, query, args...)
 }
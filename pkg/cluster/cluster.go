@@ -0,0 +1,194 @@
+// 使用方法：校验 ON CLUSTER 集群名并跟踪分布式 DDL 在各节点的执行状态
+// 通过 system.clusters 获取节点列表，通过 system.distributed_ddl_queue 轮询完成情况
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"clickhouse-ttl-tool/pkg/client"
+)
+
+// pollInterval 轮询 system.distributed_ddl_queue 的间隔
+const pollInterval = time.Second
+
+// Host 集群节点
+type Host struct {
+	Name string // 节点主机名（与集群配置一致）
+	Port uint16 // 节点端口
+}
+
+// String 返回 host:port 形式的节点描述
+func (h Host) String() string {
+	return fmt.Sprintf("%s:%d", h.Name, h.Port)
+}
+
+// HostStatus 单个节点的 DDL 执行状态
+type HostStatus struct {
+	Host     Host   // 节点
+	Finished bool   // 是否执行完成
+	Error    string // 执行失败或超时原因，成功时为空
+}
+
+// Tracker 分布式 DDL 跟踪器
+type Tracker struct {
+	client  *client.Client
+	name    string
+	hosts   []Host
+	timeout time.Duration
+}
+
+// NewTracker 创建跟踪器，并校验集群名是否存在于 system.clusters
+func NewTracker(ctx context.Context, client *client.Client, name string, timeout time.Duration) (*Tracker, error) {
+	query := `
+		SELECT DISTINCT host_name, port
+		FROM system.clusters
+		WHERE cluster = ?
+		ORDER BY host_name, port
+	`
+
+	rows, err := client.Query(ctx, query, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query system.clusters: %w", err)
+	}
+
+	var hosts []Host
+	for _, row := range rows {
+		hostName, ok := row["host_name"].(string)
+		if !ok {
+			continue
+		}
+		port, ok := row["port"].(uint16)
+		if !ok {
+			continue
+		}
+		hosts = append(hosts, Host{Name: hostName, Port: port})
+	}
+
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("cluster %s not found in system.clusters", name)
+	}
+
+	return &Tracker{
+		client:  client,
+		name:    name,
+		hosts:   hosts,
+		timeout: timeout,
+	}, nil
+}
+
+// Name 返回集群名
+func (t *Tracker) Name() string {
+	return t.name
+}
+
+// Hosts 返回集群的所有节点
+func (t *Tracker) Hosts() []Host {
+	return t.hosts
+}
+
+// Wait 等待指定表最近一次 TTL 相关的分布式 DDL 在所有节点上完成
+// since 为提交 DDL 的时间，用于排除更早的任务
+// 超时未完成的节点以超时原因返回，不视为错误
+func (t *Tracker) Wait(ctx context.Context, database, table string, since time.Time) ([]HostStatus, error) {
+	deadline := time.Now().Add(t.timeout)
+
+	for {
+		statuses, err := t.poll(ctx, database, table, since)
+		if err != nil {
+			return nil, err
+		}
+
+		if allFinished(statuses) || time.Now().After(deadline) {
+			for i := range statuses {
+				if !statuses[i].Finished && statuses[i].Error == "" {
+					statuses[i].Error = fmt.Sprintf("timeout after %s", t.timeout)
+				}
+			}
+			return statuses, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// poll 查询一次各节点的执行状态
+func (t *Tracker) poll(ctx context.Context, database, table string, since time.Time) ([]HostStatus, error) {
+	// 队列中保存的是服务端格式化后的语句，表名可能带或不带反引号
+	query := `
+		SELECT
+			ifNull(host, '') AS host,
+			toUInt16(ifNull(port, 0)) AS port,
+			toString(status) AS status,
+			toUInt16(ifNull(exception_code, 0)) AS exception_code,
+			ifNull(exception_text, '') AS exception_text
+		FROM system.distributed_ddl_queue
+		WHERE cluster = ?
+		  AND entry = (
+			  SELECT entry
+			  FROM system.distributed_ddl_queue
+			  WHERE cluster = ?
+			    AND query_create_time >= toDateTime(?)
+			    AND query LIKE '%TTL%'
+			    AND (position(query, ?) > 0 OR position(query, ?) > 0)
+			  ORDER BY query_create_time DESC, entry DESC
+			  LIMIT 1
+		  )
+	`
+
+	// 不带反引号的表名前后加空格按完整单词匹配，避免 db.events 误匹配 db.events_local、db.events2
+	plain := " " + database + "." + table + " "
+	quoted := "`" + database + "`.`" + table + "`"
+	// 预留几秒容忍客户端与服务端的时钟偏差
+	sinceSec := since.Add(-5 * time.Second).Unix()
+
+	rows, err := t.client.Query(ctx, query, t.name, t.name, sinceSec, plain, quoted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query system.distributed_ddl_queue: %w", err)
+	}
+
+	reported := make(map[Host]HostStatus)
+	for _, row := range rows {
+		hostName, _ := row["host"].(string)
+		port, _ := row["port"].(uint16)
+		status, _ := row["status"].(string)
+		code, _ := row["exception_code"].(uint16)
+		text, _ := row["exception_text"].(string)
+
+		host := Host{Name: hostName, Port: port}
+		hs := HostStatus{Host: host, Finished: strings.EqualFold(status, "Finished")}
+		if code != 0 {
+			hs.Finished = true
+			hs.Error = fmt.Sprintf("code %d: %s", code, text)
+		}
+		reported[host] = hs
+	}
+
+	// 以 system.clusters 中的节点为准，队列中尚未出现的节点视为未完成
+	statuses := make([]HostStatus, 0, len(t.hosts))
+	for _, h := range t.hosts {
+		if hs, ok := reported[h]; ok {
+			statuses = append(statuses, hs)
+		} else {
+			statuses = append(statuses, HostStatus{Host: h})
+		}
+	}
+
+	return statuses, nil
+}
+
+// allFinished 判断是否所有节点都已完成（成功或失败）
+func allFinished(statuses []HostStatus) bool {
+	for _, s := range statuses {
+		if !s.Finished {
+			return false
+		}
+	}
+	return true
+}
@@ -6,22 +6,83 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"time"
 )
 
 // Config 定义 ClickHouse 连接和 TTL 设置的配置
 type Config struct {
-	Host          string // ClickHouse 服务器地址
-	Port          int    // ClickHouse Native 协议端口
-	User          string // 用户名
-	Password      string // 密码
-	Database      string // 目标数据库名
-	RetentionDays int    // 数据保留天数
-	DryRun        bool   // 是否为预览模式（不实际执行）
-	Verbose       bool   // 是否输出详细日志
+	Host          string        // ClickHouse 服务器地址
+	Port          int           // ClickHouse Native 协议端口
+	User          string        // 用户名
+	Password      string        // 密码
+	Databases     []string      // 目标数据库名或匹配模式（glob 或 re: 前缀的正则）
+	AllDatabases  bool          // 是否处理所有非系统数据库
+	RetentionDays int           // 数据保留天数（未提供策略文件时应用于所有表）
+	PolicyFile    string        // 按表定义保留策略的 YAML 文件路径
+	Include       []string      // 仅处理匹配的表（glob 或 re: 前缀的正则）
+	Exclude       []string      // 排除匹配的表（glob 或 re: 前缀的正则）
+	Cluster       string        // ON CLUSTER 集群名，为空时仅在当前连接的节点执行
+	DDLTimeout    time.Duration // 等待 ON CLUSTER 语句在各节点完成的超时时间
+	Concurrency   int           // 并发处理的表数
+	// 限流：执行 ALTER 前服务端负载超过阈值时暂停，阈值为 0 表示不检查该项
+	ThrottlePause        time.Duration // 相邻两次 ALTER 的最小间隔
+	ThrottleMaxMerges    int           // 正在进行的合并数上限
+	ThrottleMaxMutations int           // 未完成的 mutation 数上限
+	ThrottleMaxParts     int           // 单个分区的 part 数上限
+	ThrottleMaxPoolUsage float64       // 合并/mutation 后台线程池使用率上限（0~1）
+	ThrottleInterval     time.Duration // 负载超过阈值时的检查间隔
+	ThrottleMaxWait      time.Duration // 单个表最长等待时间，超过后该表执行失败
+	NoMaterialize        bool          // 修改 TTL 时设置 materialize_ttl_after_modify=0，不重写已有数据
+	Wait                 bool          // 是否等待 ALTER 产生的 mutation 完成
+	WaitTimeout          time.Duration // 等待单个表的 mutation 完成的超时时间
+	WaitLookupTimeout    time.Duration // 复制表或 ON CLUSTER 时等待 mutation 出现的最长时间
+	TimeCandidates       []string      // 时间字段候选（列名、glob 或 re: 前缀的正则），按优先级排列
+	StateDir             string        // 运行状态目录，记录修改前的 TTL 供 rollback 使用
+	Output               string        // 报告格式：text / json / csv
+	ReportFile           string        // 报告输出文件，为空时输出到标准输出
+	Yes                  bool          // 是否跳过交互确认
+	Confirm              string        // 非交互方式提供的确认内容
+	DryRun               bool          // 是否为预览模式（不实际执行）
+	Verbose              bool          // 是否输出详细日志
 }
 
 // Validate 验证配置的完整性和合法性
 func (c *Config) Validate() error {
+	if err := c.ValidateTargets(); err != nil {
+		return err
+	}
+
+	// 提供策略文件时保留天数可选，作为策略的默认规则
+	if c.PolicyFile == "" && c.RetentionDays <= 0 {
+		return fmt.Errorf("invalid retention days: %d, must be greater than 0", c.RetentionDays)
+	}
+
+	if c.RetentionDays < 0 {
+		return fmt.Errorf("invalid retention days: %d, must not be negative", c.RetentionDays)
+	}
+
+	return nil
+}
+
+// ValidateTargets 验证连接配置和目标数据库（不涉及保留策略的子命令使用）
+func (c *Config) ValidateTargets() error {
+	if err := c.ValidateConnection(); err != nil {
+		return err
+	}
+
+	if len(c.Databases) == 0 && !c.AllDatabases {
+		return errors.New("database cannot be empty, use --database or --all-databases")
+	}
+
+	if len(c.Databases) > 0 && c.AllDatabases {
+		return errors.New("--database and --all-databases are mutually exclusive")
+	}
+
+	return nil
+}
+
+// ValidateConnection 验证所有子命令共用的配置（连接、集群和报告输出）
+func (c *Config) ValidateConnection() error {
 	if c.Host == "" {
 		return errors.New("host cannot be empty")
 	}
@@ -34,12 +95,58 @@ func (c *Config) Validate() error {
 		return errors.New("user cannot be empty")
 	}
 
-	if c.Database == "" {
-		return errors.New("database cannot be empty")
+	if c.Cluster != "" && c.DDLTimeout <= 0 {
+		return fmt.Errorf("invalid ddl timeout: %s, must be greater than 0", c.DDLTimeout)
 	}
 
-	if c.RetentionDays <= 0 {
-		return fmt.Errorf("invalid retention days: %d, must be greater than 0", c.RetentionDays)
+	if c.Concurrency < 1 {
+		return fmt.Errorf("invalid concurrency: %d, must be at least 1", c.Concurrency)
+	}
+
+	if c.Wait && c.WaitTimeout <= 0 {
+		return fmt.Errorf("invalid wait timeout: %s, must be greater than 0", c.WaitTimeout)
+	}
+	if c.WaitLookupTimeout < 0 {
+		return fmt.Errorf("invalid wait lookup timeout: %s, must not be negative", c.WaitLookupTimeout)
+	}
+
+	if err := c.validateThrottle(); err != nil {
+		return err
+	}
+
+	if c.Yes && c.Confirm != "" {
+		return errors.New("--yes and --confirm are mutually exclusive")
+	}
+
+	switch c.Output {
+	case "text", "json", "csv":
+	default:
+		return fmt.Errorf("invalid output format: %s, must be one of: text, json, csv", c.Output)
+	}
+
+	return nil
+}
+
+// validateThrottle 验证限流配置
+func (c *Config) validateThrottle() error {
+	if c.ThrottleMaxMerges < 0 || c.ThrottleMaxMutations < 0 || c.ThrottleMaxParts < 0 {
+		return errors.New("throttle thresholds must not be negative")
+	}
+
+	if c.ThrottleMaxPoolUsage < 0 || c.ThrottleMaxPoolUsage > 1 {
+		return fmt.Errorf("invalid throttle pool usage: %g, must be between 0 and 1", c.ThrottleMaxPoolUsage)
+	}
+
+	if c.ThrottlePause < 0 {
+		return fmt.Errorf("invalid throttle pause: %s, must not be negative", c.ThrottlePause)
+	}
+
+	if c.ThrottleInterval <= 0 {
+		return fmt.Errorf("invalid throttle interval: %s, must be greater than 0", c.ThrottleInterval)
+	}
+
+	if c.ThrottleMaxWait <= 0 {
+		return fmt.Errorf("invalid throttle max wait: %s, must be greater than 0", c.ThrottleMaxWait)
 	}
 
 	return nil
@@ -0,0 +1,67 @@
+// 使用方法：时间字段候选列表，决定哪些列可以作为 TTL 时间字段及其优先级
+// 候选可以是精确列名、glob 或 re: 前缀的正则，可通过命令行参数和策略文件配置
+package detector
+
+import (
+	"clickhouse-ttl-tool/pkg/chtype"
+	"clickhouse-ttl-tool/pkg/matcher"
+)
+
+// DefaultCandidates 默认的时间字段候选，按优先级排列
+var DefaultCandidates = []string{"timestamp", "event_time", "created_at", "time"}
+
+// Candidates 按优先级排列的时间字段候选
+type Candidates []matcher.Pattern
+
+// ParseCandidates 解析候选列表，每项为精确列名、glob 或 re: 前缀的正则
+func ParseCandidates(values []string) (Candidates, error) {
+	patterns, err := matcher.ParseAll(values)
+	if err != nil {
+		return nil, err
+	}
+	return Candidates(patterns), nil
+}
+
+// Strings 返回候选的描述，用于输出配置信息
+func (c Candidates) Strings() []string {
+	values := make([]string, 0, len(c))
+	for _, p := range c {
+		values = append(values, p.String())
+	}
+	return values
+}
+
+// Rank 返回可以作为时间字段的列名，按优先级排序：
+//  1. 匹配候选的列，按候选顺序排列，同一候选匹配多列时按列位置
+//  2. 未匹配任何候选的日期时间类型列，按列位置
+//
+// 整数和文本列可能保存任意数据，只有匹配候选时才会返回
+func (c Candidates) Rank(columns []Column) []string {
+	var ranked []string
+	taken := make(map[string]bool)
+
+	for _, p := range c {
+		for _, col := range columns {
+			if taken[col.Name] || !p.Match(col.Name) || !isTimeLike(col.Type) {
+				continue
+			}
+			taken[col.Name] = true
+			ranked = append(ranked, col.Name)
+		}
+	}
+
+	for _, col := range columns {
+		if !taken[col.Name] && chtype.Parse(col.Type).IsDateTime() {
+			taken[col.Name] = true
+			ranked = append(ranked, col.Name)
+		}
+	}
+
+	return ranked
+}
+
+// isTimeLike 判断列类型是否可能保存时间（日期时间、整数时间戳或文本时间）
+func isTimeLike(colType string) bool {
+	t := chtype.Parse(colType)
+	return t.IsDateTime() || t.IsInteger() || t.IsString()
+}
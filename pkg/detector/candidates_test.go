@@ -0,0 +1,57 @@
+package detector
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCandidatesRank(t *testing.T) {
+	columns := []Column{
+		{Name: "id", Type: "UInt64"},
+		{Name: "updated_at", Type: "Nullable(DateTime)"},
+		{Name: "created_at", Type: "DateTime64(3, 'UTC')"},
+		{Name: "ts", Type: "UInt64"},
+		{Name: "payload_at", Type: "Map(String, String)"},
+		{Name: "event_date", Type: "Date"},
+		{Name: "log_time", Type: "LowCardinality(String)"},
+	}
+
+	tests := []struct {
+		name       string
+		candidates []string
+		want       []string
+	}{
+		{
+			name:       "default candidates",
+			candidates: DefaultCandidates,
+			want:       []string{"created_at", "updated_at", "event_date"},
+		},
+		{
+			name:       "regex and exact",
+			candidates: []string{"re:_at$", "ts"},
+			want:       []string{"updated_at", "created_at", "ts", "event_date"},
+		},
+		{
+			name:       "glob matches text column",
+			candidates: []string{"*_time"},
+			want:       []string{"log_time", "updated_at", "created_at", "event_date"},
+		},
+		{
+			name:       "anchored values are regex",
+			candidates: []string{"^ts$", ".*_at$"},
+			want:       []string{"ts", "updated_at", "created_at", "event_date"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := ParseCandidates(tt.candidates)
+			if err != nil {
+				t.Fatalf("ParseCandidates() error = %v", err)
+			}
+			if got := c.Rank(columns); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Rank() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,14 +1,15 @@
 // 使用方法：自动检测表中的时间字段
-// 按优先级 timestamp -> event_time -> created_at 查找
-// 支持 DateTime/DateTime64/UInt64(纳秒)类型
+// 按候选列表的优先级查找（默认 timestamp -> event_time -> created_at -> time）
+// 支持 DateTime/DateTime64 类型、秒/毫秒/微秒/纳秒精度的整数时间戳和文本时间
 package detector
 
 import (
 	"context"
 	"errors"
 	"fmt"
-	"strings"
+	"math"
 
+	"clickhouse-ttl-tool/pkg/chtype"
 	"clickhouse-ttl-tool/pkg/client"
 	"clickhouse-ttl-tool/pkg/utils"
 )
@@ -20,62 +21,116 @@ var (
 
 // Detector 时间字段检测器
 type Detector struct {
-	client *client.Client
+	client     *client.Client
+	candidates Candidates
+	timeFormat string // 文本时间的格式（parseDateTime 语法），为空时按常见格式自动解析
 }
 
 // TimeColumn 时间字段信息
 type TimeColumn struct {
-	Name     string // 字段名
-	Type     string // 字段类型（原始类型）
-	IsUInt64 bool   // 是否为 UInt64 纳秒时间戳
+	Name      string    // 字段名
+	Type      string    // 字段类型（原始类型）
+	Precision Precision // 整数时间戳的精度，日期时间类型为 PrecisionNone
+	Nullable  bool      // 是否为 Nullable（TTL 表达式中需要去掉 Nullable）
+	IsString  bool      // 是否为文本时间（TTL 中需要 parseDateTimeBestEffort 解析）
+	Format    string    // 文本时间的格式，为空时使用 parseDateTimeBestEffort 解析
+	Reason    string    // 选择该字段的依据（如参与分区键），由调用方填写
+	// 时间为 NULL 或文本无法解析的行是否立即过期，由策略规则显式开启（expire_invalid_time），默认这些行不过期
+	ExpireInvalid bool
+}
+
+// Precision 整数时间戳的精度
+type Precision int
+
+const (
+	// PrecisionNone 不是整数时间戳
+	PrecisionNone Precision = iota
+	// PrecisionSeconds 秒
+	PrecisionSeconds
+	// PrecisionMillis 毫秒
+	PrecisionMillis
+	// PrecisionMicros 微秒
+	PrecisionMicros
+	// PrecisionNanos 纳秒
+	PrecisionNanos
+)
+
+// String 返回精度的简写
+func (p Precision) String() string {
+	switch p {
+	case PrecisionSeconds:
+		return "s"
+	case PrecisionMillis:
+		return "ms"
+	case PrecisionMicros:
+		return "us"
+	case PrecisionNanos:
+		return "ns"
+	default:
+		return ""
+	}
+}
+
+// Divisor 返回将该精度的时间戳转换为秒需要除以的数
+func (p Precision) Divisor() uint64 {
+	switch p {
+	case PrecisionMillis:
+		return 1000
+	case PrecisionMicros:
+		return 1000000
+	case PrecisionNanos:
+		return 1000000000
+	default:
+		return 1
+	}
+}
+
+// precisionRanges 各精度时间戳的取值范围 [min, max)
+// 下限约为 1973 年，上限约为 5138 年，范围之间互不重叠
+var precisionRanges = []struct {
+	precision Precision
+	min, max  uint64
+}{
+	{PrecisionSeconds, 1e8, 1e11},
+	{PrecisionMillis, 1e11, 1e14},
+	{PrecisionMicros, 1e14, 1e17},
+	{PrecisionNanos, 1e17, math.MaxUint64},
 }
 
 // NewDetector 创建新的检测器
-func NewDetector(client *client.Client) *Detector {
+// candidates 为未指定列时使用的时间字段候选
+func NewDetector(client *client.Client, candidates Candidates) *Detector {
 	return &Detector{
-		client: client,
+		client:     client,
+		candidates: candidates,
 	}
 }
 
+// WithTimeFormat 返回使用指定文本时间格式的检测器副本
+// format 为 parseDateTime 的格式（MySQL 语法，如 %Y%m%d%H%i%s），文本列按该格式采样和解析
+func (d *Detector) WithTimeFormat(format string) *Detector {
+	c := *d
+	c.timeFormat = format
+	return &c
+}
+
 // DetectTimeColumn 检测表的时间字段
 // 如果提供了 preferredColumns，则优先检测这些列
 func (d *Detector) DetectTimeColumn(ctx context.Context, database, table string, preferredColumns ...string) (*TimeColumn, error) {
-	// 查询表的所有字段信息
-	query := `
-		SELECT
-			name,
-			type
-		FROM system.columns
-		WHERE database = ?
-		  AND table = ?
-		ORDER BY position
-	`
-
-	rows, err := d.client.Query(ctx, query, database, table)
+	list, err := d.ListColumns(ctx, database, table)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query columns: %w", err)
+		return nil, err
 	}
 
-	// 构建字段映射
-	columns := make(map[string]string)
-	for _, row := range rows {
-		name, ok := row["name"].(string)
-		if !ok {
-			continue
-		}
-		colType, ok := row["type"].(string)
-		if !ok {
-			continue
-		}
-		columns[name] = colType
+	columns := make(map[string]string, len(list))
+	for _, c := range list {
+		columns[c.Name] = c.Type
 	}
 
-	// 构建候选列表：优先使用 preferredColumns，然后是默认候选
-	var candidates []string
-	if len(preferredColumns) > 0 {
-		candidates = preferredColumns
-	} else {
-		candidates = []string{"timestamp", "event_time", "created_at"}
+	// 构建候选列表：优先使用 preferredColumns，否则按候选列表排序
+	candidates := preferredColumns
+	if len(candidates) == 0 {
+		candidates = d.candidates.Rank(list)
 	}
 
 	// 按优先级检测时间字段
@@ -85,51 +140,125 @@ func (d *Detector) DetectTimeColumn(ctx context.Context, database, table string,
 			continue
 		}
 
+		// 去掉 Nullable / LowCardinality 包装后判断类型
+		t := chtype.Parse(colType)
+
 		// 检查是否为时间类型
-		if isDateTimeType(colType) {
+		if t.IsDateTime() {
 			return &TimeColumn{
 				Name:     name,
 				Type:     colType,
-				IsUInt64: false,
+				Nullable: t.Nullable,
 			}, nil
 		}
 
-		// 检查是否为 UInt64（可能是纳秒时间戳）
-		if strings.HasPrefix(colType, "UInt64") {
-			// 采样检查是否为纳秒级别时间戳
-			isNano, err := d.isNanoTimestamp(ctx, database, table, name)
+		// 检查是否为整数时间戳，采样判断精度
+		if t.IsInteger() {
+			precision, err := d.samplePrecision(ctx, database, table, name)
 			if err != nil {
-				// 采样失败，跳过此字段
+				// 采样失败或无法判断精度，跳过此字段
 				continue
 			}
 
-			if isNano {
-				return &TimeColumn{
-					Name:     name,
-					Type:     colType,
-					IsUInt64: true,
-				}, nil
+			return &TimeColumn{
+				Name:      name,
+				Type:      colType,
+				Precision: precision,
+				Nullable:  t.Nullable,
+			}, nil
+		}
+
+		// 检查是否为文本时间，采样判断能否解析
+		if t.IsString() {
+			if err := d.sampleStringTime(ctx, database, table, name); err != nil {
+				// 采样失败或无法解析，跳过此字段
+				continue
 			}
+
+			return &TimeColumn{
+				Name:     name,
+				Type:     colType,
+				Nullable: t.Nullable,
+				IsString: true,
+				Format:   d.timeFormat,
+			}, nil
 		}
 	}
 
 	return nil, ErrNoTimeColumn
 }
 
-// isDateTimeType 判断是否为日期时间类型
-func isDateTimeType(colType string) bool {
-	// 支持 DateTime, DateTime64, Date 等类型
-	return strings.HasPrefix(colType, "DateTime") ||
-		strings.HasPrefix(colType, "Date")
+// RankColumns 查询表的字段并按 candidates 排序，返回可以作为时间字段的列名
+func (d *Detector) RankColumns(ctx context.Context, database, table string, candidates Candidates) ([]string, error) {
+	columns, err := d.ListColumns(ctx, database, table)
+	if err != nil {
+		return nil, err
+	}
+	return candidates.Rank(columns), nil
 }
 
-// isNanoTimestamp 采样判断是否为纳秒级别时间戳
-// 纳秒时间戳通常 > 1e17 (约 2001 年以后)
-// 调整阈值从 1e15 到 1e17 以提高精确度
-func (d *Detector) isNanoTimestamp(ctx context.Context, database, table, column string) (bool, error) {
+// Column 表字段信息
+type Column struct {
+	Name           string // 字段名
+	Type           string // 字段类型
+	InPrimaryKey   bool   // 是否属于主键
+	InSortingKey   bool   // 是否属于排序键
+	InPartitionKey bool   // 是否属于分区键
+}
+
+// ListColumns 按位置顺序查询表的所有字段
+func (d *Detector) ListColumns(ctx context.Context, database, table string) ([]Column, error) {
+	// 查询表的所有字段信息
+	query := `
+		SELECT
+			name,
+			type,
+			is_in_primary_key,
+			is_in_sorting_key,
+			is_in_partition_key
+		FROM system.columns
+		WHERE database = ?
+		  AND table = ?
+		ORDER BY position
+	`
+
+	rows, err := d.client.Query(ctx, query, database, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query columns: %w", err)
+	}
+
+	columns := make([]Column, 0, len(rows))
+	for _, row := range rows {
+		name, ok := row["name"].(string)
+		if !ok {
+			continue
+		}
+		colType, ok := row["type"].(string)
+		if !ok {
+			continue
+		}
+		inPrimaryKey, _ := row["is_in_primary_key"].(uint8)
+		inSortingKey, _ := row["is_in_sorting_key"].(uint8)
+		inPartitionKey, _ := row["is_in_partition_key"].(uint8)
+		columns = append(columns, Column{
+			Name:           name,
+			Type:           colType,
+			InPrimaryKey:   inPrimaryKey == 1,
+			InSortingKey:   inSortingKey == 1,
+			InPartitionKey: inPartitionKey == 1,
+		})
+	}
+
+	return columns, nil
+}
+
+// samplePrecision 采样判断整数时间戳的精度
+// 所有采样值必须落在同一精度的取值范围内，否则视为不是时间戳
+func (d *Detector) samplePrecision(ctx context.Context, database, table, column string) (Precision, error) {
 	// 采样查询前 10 行，使用标识符转义防止 SQL 注入
+	// 统一转换为 UInt64，避免 Nullable / LowCardinality 和不同宽度整数的扫描差异（NULL 和非正数已被 WHERE 排除）
 	query := fmt.Sprintf(
-		"SELECT %s FROM %s.%s WHERE %s > 0 LIMIT 10",
+		"SELECT toUInt64(assumeNotNull(%s)) AS value FROM %s.%s WHERE %s > 0 LIMIT 10",
 		utils.EscapeIdentifier(column),
 		utils.EscapeIdentifier(database),
 		utils.EscapeIdentifier(table),
@@ -139,53 +268,102 @@ func (d *Detector) isNanoTimestamp(ctx context.Context, database, table, column
 	rows, err := d.client.Query(ctx, query)
 	if err != nil {
 		// 区分错误类型，提供更友好的错误信息
-		return false, fmt.Errorf("failed to sample column %s: %w", column, err)
+		return PrecisionNone, fmt.Errorf("failed to sample column %s: %w", column, err)
 	}
 
 	if len(rows) == 0 {
-		// 表为空或字段全为 0，这是正常情况，不应该报错
-		// 返回 false 表示无法判断，让调用方跳过此字段
-		return false, fmt.Errorf("no data to sample in column %s", column)
+		// 表为空或字段全为 0，无法判断精度，让调用方跳过此字段
+		return PrecisionNone, fmt.Errorf("no data to sample in column %s", column)
 	}
 
 	// 检查采样值
+	detected := PrecisionNone
 	for _, row := range rows {
-		val, ok := row[column]
+		numVal, ok := row["value"].(uint64)
 		if !ok {
 			continue
 		}
 
-		// 尝试转换为数值
-		var numVal uint64
-		switch v := val.(type) {
-		case uint64:
-			numVal = v
-		case int64:
-			numVal = uint64(v)
-		case uint32:
-			numVal = uint64(v)
-		case int32:
-			numVal = uint64(v)
-		case uint:
-			numVal = uint64(v)
-		case int:
-			numVal = uint64(v)
-		default:
-			continue
+		precision := classifyPrecision(numVal)
+		if precision == PrecisionNone {
+			return PrecisionNone, fmt.Errorf("value %d in column %s is not a timestamp", numVal, column)
 		}
-
-		// 纳秒时间戳判断：> 1e17
-		// 时间戳范围参考：
-		//   秒级:     ~1e9  (2001年约 1000000000)
-		//   毫秒级:   ~1e12 (2001年约 1000000000000)
-		//   微秒级:   ~1e15 (2001年约 1000000000000000)
-		//   纳秒级:   ~1e18 (2001年约 1000000000000000000)
-		// 使用 1e17 作为阈值可以准确区分纳秒级别
-		const nanoThreshold = 1e17
-		if numVal > nanoThreshold {
-			return true, nil
+		if detected != PrecisionNone && precision != detected {
+			return PrecisionNone, fmt.Errorf("column %s mixes %s and %s timestamps", column, detected, precision)
 		}
+		detected = precision
 	}
 
-	return false, nil
+	if detected == PrecisionNone {
+		return PrecisionNone, fmt.Errorf("no integer values sampled in column %s", column)
+	}
+
+	return detected, nil
+}
+
+// sampleStringTime 采样判断文本列是否保存时间
+// 未指定格式时使用与 TTL 相同的 parseDateTimeBestEffort 解析，支持 ISO-8601、RFC 1123、
+// YYYY-MM-DD hh:mm:ss、DD/MM/YYYY 和 Unix 时间戳等常见格式；指定格式时使用 parseDateTime 按格式解析；
+// 所有非空采样值都必须解析成功且晚于 1973 年
+func (d *Detector) sampleStringTime(ctx context.Context, database, table, column string) error {
+	query := fmt.Sprintf(`
+		SELECT
+			count() AS sampled,
+			countIf(parsed >= toDateTime(100000000)) AS valid
+		FROM (
+			SELECT %s AS parsed
+			FROM %s.%s
+			WHERE %s != ''
+			LIMIT 10
+		)`,
+		ParseTimeExpr(fmt.Sprintf("assumeNotNull(%s)", utils.EscapeIdentifier(column)), d.timeFormat, "OrNull"),
+		utils.EscapeIdentifier(database),
+		utils.EscapeIdentifier(table),
+		utils.EscapeIdentifier(column),
+	)
+
+	rows, err := d.client.Query(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to sample column %s: %w", column, err)
+	}
+	if len(rows) == 0 {
+		return fmt.Errorf("no data to sample in column %s", column)
+	}
+
+	sampled, _ := rows[0]["sampled"].(uint64)
+	valid, _ := rows[0]["valid"].(uint64)
+	if sampled == 0 {
+		return fmt.Errorf("no data to sample in column %s", column)
+	}
+	if valid < sampled {
+		return fmt.Errorf("%d of %d sampled values in column %s are not timestamps", sampled-valid, sampled, column)
+	}
+
+	return nil
+}
+
+// ParseTimeExpr 生成解析文本时间的表达式，expr 为已转义的列表达式
+// format 为空时使用 parseDateTimeBestEffort，否则使用 parseDateTime 按格式解析；
+// suffix 为 OrNull（无法解析时为 NULL）或 OrZero（无法解析时为 1970-01-01）
+func ParseTimeExpr(expr, format, suffix string) string {
+	if format == "" {
+		return fmt.Sprintf("parseDateTimeBestEffort%s(%s)", suffix, expr)
+	}
+	return fmt.Sprintf("parseDateTime%s(%s, %s)", suffix, expr, utils.EscapeString(format))
+}
+
+// classifyPrecision 根据数值大小判断时间戳精度
+// 时间戳范围参考（2001 年）：
+//
+//	秒级:     ~1e9
+//	毫秒级:   ~1e12
+//	微秒级:   ~1e15
+//	纳秒级:   ~1e18
+func classifyPrecision(v uint64) Precision {
+	for _, r := range precisionRanges {
+		if v >= r.min && v < r.max {
+			return r.precision
+		}
+	}
+	return PrecisionNone
 }
@@ -0,0 +1,45 @@
+package detector
+
+import "testing"
+
+func TestClassifyPrecision(t *testing.T) {
+	tests := []struct {
+		v    uint64
+		want Precision
+	}{
+		{0, PrecisionNone},
+		{99_999_999, PrecisionNone},
+		{100_000_000, PrecisionSeconds},
+		{1_704_067_200, PrecisionSeconds},           // 2024-01-01 秒
+		{1_704_067_200_000, PrecisionMillis},        // 2024-01-01 毫秒
+		{1_704_067_200_000_000, PrecisionMicros},    // 2024-01-01 微秒
+		{1_704_067_200_000_000_000, PrecisionNanos}, // 2024-01-01 纳秒
+		{99_999_999_999, PrecisionSeconds},
+		{100_000_000_000, PrecisionMillis},
+		{100_000_000_000_000_000, PrecisionNanos},
+	}
+
+	for _, tt := range tests {
+		if got := classifyPrecision(tt.v); got != tt.want {
+			t.Errorf("classifyPrecision(%d) = %v, want %v", tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestParseTimeExpr(t *testing.T) {
+	tests := []struct {
+		format, suffix string
+		want           string
+	}{
+		{"", "OrNull", "parseDateTimeBestEffortOrNull(`log_time`)"},
+		{"", "OrZero", "parseDateTimeBestEffortOrZero(`log_time`)"},
+		{"%Y%m%d%H%i%s", "OrNull", "parseDateTimeOrNull(`log_time`, '%Y%m%d%H%i%s')"},
+		{"%d/%m/%Y", "OrZero", "parseDateTimeOrZero(`log_time`, '%d/%m/%Y')"},
+	}
+
+	for _, tt := range tests {
+		if got := ParseTimeExpr("`log_time`", tt.format, tt.suffix); got != tt.want {
+			t.Errorf("ParseTimeExpr(%q, %q) = %q, want %q", tt.format, tt.suffix, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+// 使用方法：根据表的分区键和排序键调整时间字段的优先级
+// TTL 字段参与分区时，过期数据集中在旧分区，合并时可以整个 part 删除，代价远低于逐行重写
+package detector
+
+import (
+	"fmt"
+	"strings"
+
+	"clickhouse-ttl-tool/pkg/lexer"
+)
+
+// Keys 表的分区键和排序键表达式（system.tables.partition_key / sorting_key）
+type Keys struct {
+	Partition string // 分区键，如 toYYYYMM(event_time)
+	Sorting   string // 排序键，如 tenant_id, event_time
+}
+
+// keyRole 字段在表键中的作用，数值越小优先级越高
+type keyRole int
+
+const (
+	rolePartition keyRole = iota // 参与分区键
+	roleSorting                  // 参与排序键
+	roleNone                     // 不参与分区键和排序键
+)
+
+// role 返回字段在表键中的作用
+func (k Keys) role(column string) keyRole {
+	switch {
+	case keyReferences(k.Partition, column):
+		return rolePartition
+	case keyReferences(k.Sorting, column):
+		return roleSorting
+	default:
+		return roleNone
+	}
+}
+
+// Order 按字段是否参与分区键、排序键重新排列候选列，同一类中保持原有的候选顺序
+func (k Keys) Order(columns []string) []string {
+	ordered := make([]string, 0, len(columns))
+	for _, r := range []keyRole{rolePartition, roleSorting, roleNone} {
+		for _, c := range columns {
+			if k.role(c) == r {
+				ordered = append(ordered, c)
+			}
+		}
+	}
+	return ordered
+}
+
+// Reason 返回选择该字段作为时间字段的依据，用于报告
+func (k Keys) Reason(column string) string {
+	switch k.role(column) {
+	case rolePartition:
+		return fmt.Sprintf("参与分区键 %s，过期数据可以按整个 part 删除", k.Partition)
+	case roleSorting:
+		return fmt.Sprintf("参与排序键 %s（未参与分区键，过期数据在合并时逐行删除）", k.Sorting)
+	default:
+		if strings.TrimSpace(k.Partition) != "" {
+			return fmt.Sprintf("按候选优先级选择（未参与分区键 %s，过期数据在合并时逐行删除）", k.Partition)
+		}
+		return "按候选优先级选择（表未分区）"
+	}
+}
+
+// keyReferences 判断键表达式是否引用了指定列
+func keyReferences(expr, column string) bool {
+	for _, c := range keyColumns(expr) {
+		if c == column {
+			return true
+		}
+	}
+	return false
+}
+
+// keyColumns 提取键表达式中引用的列名
+// 跳过字符串字面量、数字和函数名（紧跟左括号的标识符），支持反引号和双引号包围的列名
+//
+//	"toYYYYMM(event_time)"        -> [event_time]
+//	"tenant_id, intDiv(ts, 1000)" -> [tenant_id ts]
+func keyColumns(expr string) []string {
+	// 键表达式来自 system.tables，引号未闭合时仍使用已识别的部分
+	tokens, _ := lexer.Tokenize(expr)
+
+	var columns []string
+	for i, t := range tokens {
+		if t.IsIdent() && !lexer.IsCall(tokens, i) {
+			columns = append(columns, t.Value)
+		}
+	}
+	return columns
+}
@@ -0,0 +1,74 @@
+package detector
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestKeyColumns(t *testing.T) {
+	// 表达式取自 system.tables 的 partition_key / sorting_key
+	tests := []struct {
+		expr string
+		want []string
+	}{
+		{"", nil},
+		{"toYYYYMM(event_time)", []string{"event_time"}},
+		{"tenant_id, event_time", []string{"tenant_id", "event_time"}},
+		{"(tenant_id, toDate(ts))", []string{"tenant_id", "ts"}},
+		{"tenant_id, intDiv(ts, 1000)", []string{"tenant_id", "ts"}},
+		{"toStartOfInterval(created_at, toIntervalHour(1))", []string{"created_at"}},
+		{"`event time`, \"user\"", []string{"event time", "user"}},
+		{"formatDateTime(ts, '%Y%m'), attrs.kind", []string{"ts", "attrs.kind"}},
+		{"tuple()", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			if got := keyColumns(tt.expr); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("keyColumns(%q) = %q, want %q", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeysOrder(t *testing.T) {
+	tests := []struct {
+		name    string
+		keys    Keys
+		columns []string
+		want    []string
+	}{
+		{
+			name:    "partition before sorting before others",
+			keys:    Keys{Partition: "toYYYYMM(event_date)", Sorting: "tenant_id, created_at"},
+			columns: []string{"timestamp", "created_at", "event_date"},
+			want:    []string{"event_date", "created_at", "timestamp"},
+		},
+		{
+			name:    "same role keeps candidate order",
+			keys:    Keys{Sorting: "updated_at, created_at"},
+			columns: []string{"created_at", "updated_at", "ts"},
+			want:    []string{"created_at", "updated_at", "ts"},
+		},
+		{
+			name:    "column in both keys counts as partition",
+			keys:    Keys{Partition: "toDate(ts)", Sorting: "created_at, ts"},
+			columns: []string{"created_at", "ts"},
+			want:    []string{"ts", "created_at"},
+		},
+		{
+			name:    "unpartitioned table",
+			keys:    Keys{Partition: "", Sorting: "id"},
+			columns: []string{"timestamp", "event_time"},
+			want:    []string{"timestamp", "event_time"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.keys.Order(tt.columns); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Order() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+// 使用方法：比较表现有的 TTL 与将要设置的 TTL
+// 服务端会将 TTL 表达式格式化后保存（如 INTERVAL 30 DAY -> toIntervalDay(30)），比较前先做归一化
+package executor
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Change TTL 变更类型
+type Change string
+
+const (
+	// ChangeNew 表原本没有 TTL
+	ChangeNew Change = "new"
+	// ChangeChanged 表已有不同的 TTL，将被覆盖
+	ChangeChanged Change = "changed"
+	// ChangeUnchanged 表已有相同的 TTL，无需修改
+	ChangeUnchanged Change = "unchanged"
+	// ChangeRemoved 表原有的 TTL 被移除
+	ChangeRemoved Change = "removed"
+)
+
+var (
+	// intervalPattern 匹配 INTERVAL N UNIT 形式的时间间隔
+	intervalPattern = regexp.MustCompile(`(?i)\bINTERVAL\s+(\d+)\s+(SECOND|MINUTE|HOUR|DAY|WEEK|MONTH|QUARTER|YEAR)\b`)
+	// deletePattern 匹配默认的 DELETE 动作，服务端格式化时会省略
+	deletePattern = regexp.MustCompile(`(?i)\bDELETE\b`)
+)
+
+// compareTTL 比较现有 TTL 与新 TTL
+func compareTTL(oldTTL, newTTL string) Change {
+	if strings.TrimSpace(oldTTL) == "" {
+		return ChangeNew
+	}
+	if normalizeTTL(oldTTL) == normalizeTTL(newTTL) {
+		return ChangeUnchanged
+	}
+	return ChangeChanged
+}
+
+// groupingKeywords 后面跟括号时括号用于分组而不是函数调用的关键字
+var groupingKeywords = map[string]bool{
+	"and":   true,
+	"or":    true,
+	"not":   true,
+	"where": true,
+	"by":    true,
+	"set":   true,
+}
+
+// normalizeTTL 归一化 TTL 表达式
+// 统一时间间隔写法，去掉 DELETE 关键字、反引号、空白和分组括号，统一 <> / == 写法，字符串字面量之外转为小写
+//
+// 服务端保存时会为复合条件补上括号，如 WHERE a = 1 AND b = 2 保存为 WHERE (a = 1) AND (b = 2)，
+// 因此分组括号（不跟在函数名之后的括号）不参与比较，函数调用和 IN 的括号保留
+func normalizeTTL(expr string) string {
+	expr = intervalPattern.ReplaceAllStringFunc(expr, func(m string) string {
+		parts := intervalPattern.FindStringSubmatch(m)
+		unit := strings.ToLower(parts[2])
+		return "toInterval" + strings.ToUpper(unit[:1]) + unit[1:] + "(" + parts[1] + ")"
+	})
+	expr = deletePattern.ReplaceAllString(expr, "")
+
+	var (
+		b        strings.Builder
+		inString bool
+		word     []byte // 左括号之前最近的标识符（已转为小写），为空表示前面不是标识符
+		wordDone bool   // word 之后是否出现了空白，再出现标识符字符时开始新的单词
+		calls    []bool // 未闭合的括号是否为函数调用（需要保留）
+	)
+	for i := 0; i < len(expr); i++ {
+		c := expr[i]
+		if inString {
+			b.WriteByte(c)
+			if c == '\\' && i+1 < len(expr) {
+				i++
+				b.WriteByte(expr[i])
+			} else if c == '\'' {
+				inString = false
+			}
+			continue
+		}
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			wordDone = true
+			continue
+		case c == '`':
+			continue
+		case c == '_' || c == '.' || (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+			if c >= 'A' && c <= 'Z' {
+				c += 'a' - 'A'
+			}
+			if wordDone {
+				word = word[:0]
+				wordDone = false
+			}
+			word = append(word, c)
+			b.WriteByte(c)
+			continue
+		}
+
+		switch {
+		case c == '\'':
+			inString = true
+			b.WriteByte(c)
+		case c == '(':
+			call := len(word) > 0 && !groupingKeywords[string(word)]
+			calls = append(calls, call)
+			if call {
+				b.WriteByte(c)
+			}
+		case c == ')':
+			call := true
+			if n := len(calls); n > 0 {
+				call = calls[n-1]
+				calls = calls[:n-1]
+			}
+			if call {
+				b.WriteByte(c)
+			}
+		case c == '<' && i+1 < len(expr) && expr[i+1] == '>':
+			i++
+			b.WriteString("!=")
+		case c == '=' && i+1 < len(expr) && expr[i+1] == '=':
+			i++
+			b.WriteByte('=')
+		default:
+			b.WriteByte(c)
+		}
+		word = word[:0]
+		wordDone = false
+	}
+
+	return b.String()
+}
@@ -0,0 +1,75 @@
+package executor
+
+import "testing"
+
+func TestNormalizeTTL(t *testing.T) {
+	// 左侧为工具生成的表达式，右侧为服务端保存在 create_table_query 中的格式
+	tests := []struct {
+		name      string
+		generated string
+		stored    string
+	}{
+		{
+			name:      "interval and backticks",
+			generated: "`event_time` + INTERVAL 30 DAY",
+			stored:    "event_time + toIntervalDay(30)",
+		},
+		{
+			name:      "delete where with grouping parentheses",
+			generated: "`ts` + INTERVAL 3 DAY DELETE WHERE level = 'debug' AND user_id <> 0, `ts` + INTERVAL 90 DAY",
+			stored:    "ts + toIntervalDay(3) WHERE (level = 'debug') AND (user_id != 0), ts + toIntervalDay(90)",
+		},
+		{
+			name:      "nested function calls",
+			generated: "ifNull(toDateTime(intDiv(`ts`, 1000)) + INTERVAL 7 DAY, toDateTime(4294967295))",
+			stored:    "ifNull(toDateTime(intDiv(ts, 1000)) + toIntervalDay(7), toDateTime(4294967295))",
+		},
+		{
+			name:      "group by and set",
+			generated: "`ts` + INTERVAL 30 DAY GROUP BY tenant_id SET `bytes` = sum(bytes)",
+			stored:    "ts + toIntervalDay(30) GROUP BY tenant_id SET bytes = sum(bytes)",
+		},
+		{
+			name:      "in list and equality",
+			generated: "`ts` + INTERVAL 1 WEEK DELETE WHERE level IN ('debug', 'trace') OR status == 1",
+			stored:    "ts + toIntervalWeek(1) WHERE (level IN ('debug', 'trace')) OR (status = 1)",
+		},
+		{
+			name:      "move to disk",
+			generated: "`ts` + INTERVAL 7 DAY TO DISK 'cold'",
+			stored:    "ts + toIntervalDay(7) TO DISK 'cold'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if g, s := normalizeTTL(tt.generated), normalizeTTL(tt.stored); g != s {
+				t.Errorf("normalizeTTL mismatch:\n generated: %q\n stored:    %q", g, s)
+			}
+		})
+	}
+}
+
+func TestCompareTTL(t *testing.T) {
+	tests := []struct {
+		name   string
+		oldTTL string
+		newTTL string
+		want   Change
+	}{
+		{"no ttl", "", "`ts` + INTERVAL 30 DAY", ChangeNew},
+		{"same", "ts + toIntervalDay(30)", "`ts` + INTERVAL 30 DAY", ChangeUnchanged},
+		{"days differ", "ts + toIntervalDay(30)", "`ts` + INTERVAL 90 DAY", ChangeChanged},
+		{"column differs", "ts + toIntervalDay(30)", "`created_at` + INTERVAL 30 DAY", ChangeChanged},
+		{"string case kept", "ts + toIntervalDay(3) WHERE level = 'Debug'", "`ts` + INTERVAL 3 DAY DELETE WHERE level = 'debug'", ChangeChanged},
+		{"call parentheses kept", "toDate(ts) + toIntervalDay(30)", "toDate ts + INTERVAL 30 DAY", ChangeChanged},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := compareTTL(tt.oldTTL, tt.newTTL); got != tt.want {
+				t.Errorf("compareTTL(%q, %q) = %v, want %v", tt.oldTTL, tt.newTTL, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,76 +1,551 @@
-// 使用方法：生成并执行 ALTER TABLE MODIFY TTL 语句
-// 支持 DateTime/DateTime64 和 UInt64(纳秒) 类型的时间字段
+// 使用方法：生成并执行 ALTER TABLE MODIFY TTL / MODIFY COLUMN ... TTL / MATERIALIZE TTL 语句
+// 支持 DateTime/DateTime64 类型、秒/毫秒/微秒/纳秒精度的整数时间戳和文本时间
+// 指定集群时生成 ON CLUSTER 语句并跟踪各节点的执行状态
 package executor
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"regexp"
+	"sort"
 	"strings"
+	"time"
 
+	"clickhouse-ttl-tool/pkg/chtype"
 	"clickhouse-ttl-tool/pkg/client"
+	"clickhouse-ttl-tool/pkg/cluster"
 	"clickhouse-ttl-tool/pkg/detector"
+	"clickhouse-ttl-tool/pkg/mutation"
+	"clickhouse-ttl-tool/pkg/policy"
 	"clickhouse-ttl-tool/pkg/scanner"
+	"clickhouse-ttl-tool/pkg/state"
+	"clickhouse-ttl-tool/pkg/throttle"
 	"clickhouse-ttl-tool/pkg/utils"
 )
 
 // Executor TTL 执行器
 type Executor struct {
-	client  *client.Client
-	dryRun  bool
-	verbose bool
+	client   *client.Client
+	dryRun   bool
+	verbose  bool
+	cluster  *cluster.Tracker
+	recorder *state.Recorder
+	throttle *throttle.Throttle
+	// 非 nil 时等待 ALTER 产生的 mutation 完成
+	mutations *mutation.Watcher
+	// 修改 TTL 时不物化已有数据
+	noMaterialize bool
+}
+
+// modifyColumnTTL 匹配设置列级 TTL 的命令（MODIFY COLUMN `c` TTL ...）
+var modifyColumnTTL = regexp.MustCompile("MODIFY COLUMN `(?:[^`]|``)*` TTL ")
+
+// Options 执行器选项
+type Options struct {
+	DryRun  bool             // 是否为预览模式
+	Verbose bool             // 是否输出详细日志
+	Cluster *cluster.Tracker // 非 nil 时生成 ON CLUSTER 语句并跟踪各节点执行状态
+	// 非 nil 时在执行 ALTER 之前记录表原有的 TTL，供 rollback 恢复
+	Recorder *state.Recorder
+	// 非 nil 时在执行 ALTER 之前等待服务端负载降到阈值以下
+	Throttle *throttle.Throttle
+	// 非 nil 时等待 ALTER 产生的 mutation 完成，mutation 失败时该表执行失败
+	Mutations *mutation.Watcher
+	// 为 true 时以 materialize_ttl_after_modify=0 执行，修改 TTL 不重写已有数据
+	NoMaterialize bool
 }
 
 // ExecutionResult 执行结果
 type ExecutionResult struct {
-	Database   string // 数据库名
-	Table      string // 表名
-	TimeColumn string // 时间字段名
-	TimeType   string // 时间字段类型
-	SQL        string // 生成的 SQL 语句
+	Database string // 数据库名
+	Table    string // 表名
+	// 由分布式表解析得到本地表时，记录来源分布式表（database.table）
+	Distributed string
+	TimeColumn  string // 时间字段名
+	TimeType    string // 时间字段类型
+	TimeReason  string // 选择该时间字段的依据
+	Rule        string // 匹配的策略规则
+	Retention   int    // 保留天数（多条 TTL 子句时为删除子句中最长的保留天数）
+	SQL         string // 生成的 SQL 语句
+	OldTTL      string // 执行前的表级 TTL 表达式，没有时为空
+	NewTTL      string // 生成的 TTL 表达式（不修改表级 TTL 时与 OldTTL 相同）
+	// 需要修改的列级 TTL（未变化的列不包含在内）
+	Columns    []state.ColumnTTL
+	Change     Change // 与现有 TTL 的比较结果
+	Checksum   string // 生成 SQL 时的表结构校验和
 	Success    bool   // 是否执行成功
 	Error      error  // 错误信息
 	Skipped    bool   // 是否跳过
 	SkipReason string // 跳过原因
+	// 执行失败或超时的集群节点（host:port: 原因），仅 ON CLUSTER 模式
+	FailedHosts []string
+	// ON CLUSTER 模式下执行失败，但 ALTER 可能已在部分节点生效（有节点成功、超时或无法跟踪）
+	Partial bool
+	// 执行前因服务端负载过高而等待的时长
+	Waited time.Duration
+	// 逐个物化 TTL 的分区 ID，仅 materialize 子命令
+	Partitions []string
+	// 等待到的 mutation 最终状态，仅 --wait 模式
+	Mutation *mutation.Status
+	// 需要使用者注意的问题（如 Nullable 时间字段的 NULL 值处理）
+	Warnings []string
+}
+
+// ColumnRule 列级 TTL 设置
+type ColumnRule struct {
+	Column        string // 列名
+	RetentionDays int    // 列数据保留天数
 }
 
 // NewExecutor 创建新的执行器
-func NewExecutor(client *client.Client, dryRun, verbose bool) *Executor {
+func NewExecutor(client *client.Client, opts Options) *Executor {
 	return &Executor{
-		client:  client,
-		dryRun:  dryRun,
-		verbose: verbose,
+		client:        client,
+		dryRun:        opts.DryRun,
+		verbose:       opts.Verbose,
+		cluster:       opts.Cluster,
+		recorder:      opts.Recorder,
+		throttle:      opts.Throttle,
+		mutations:     opts.Mutations,
+		noMaterialize: opts.NoMaterialize,
 	}
 }
 
 // Execute 执行 TTL 设置
+// rules 为策略规则中的表级 TTL 子句，为空时不修改表级 TTL；columns 为需要设置列级 TTL 的列
 func (e *Executor) Execute(
 	ctx context.Context,
 	table scanner.TableInfo,
 	timeCol *detector.TimeColumn,
-	retentionDays int,
+	rules []policy.TTLRule,
+	columns []ColumnRule,
 ) ExecutionResult {
 	result := ExecutionResult{
-		Database:   table.Database,
-		Table:      table.Table,
-		TimeColumn: timeCol.Name,
-		TimeType:   timeCol.Type,
-		Success:    false,
+		Database:    table.Database,
+		Table:       table.Table,
+		Distributed: distributedName(table),
+		TimeColumn:  timeCol.Name,
+		TimeType:    timeType(timeCol),
+		TimeReason:  timeCol.Reason,
+		Retention:   maxRetention(rules),
+		Checksum:    table.Checksum,
+		Success:     false,
+		OldTTL:      table.CurrentTTL,
+		NewTTL:      table.CurrentTTL,
+		Change:      ChangeUnchanged,
+		Warnings:    timeWarnings(timeCol),
+	}
+
+	// 表级 TTL：仅在与现有 TTL 不同时修改
+	var commands []string
+	if len(rules) > 0 {
+		result.NewTTL = generateTTLExpr(timeCol, rules)
+		result.Change = compareTTL(result.OldTTL, result.NewTTL)
+		if result.Change != ChangeUnchanged {
+			commands = append(commands, "MODIFY TTL "+result.NewTTL)
+		}
+	}
+
+	// 列级 TTL：同样只修改有变化的列
+	columnChange := ChangeUnchanged
+	for _, c := range columns {
+		newTTL := generateTTLExpr(timeCol, []policy.TTLRule{{RetentionDays: c.RetentionDays}})
+		oldTTL := table.ColumnTTLs[c.Column]
+		change := compareTTL(oldTTL, newTTL)
+		if change == ChangeUnchanged {
+			continue
+		}
+		if columnChange != ChangeChanged {
+			columnChange = change
+		}
+		result.Columns = append(result.Columns, state.ColumnTTL{Column: c.Column, OldTTL: oldTTL, NewTTL: newTTL})
+		commands = append(commands, fmt.Sprintf("MODIFY COLUMN %s TTL %s", utils.EscapeIdentifier(c.Column), newTTL))
+	}
+	if result.Change == ChangeUnchanged {
+		result.Change = columnChange
 	}
 
-	// 生成 TTL SQL
-	sql := e.generateTTLSQL(table.Database, table.Table, timeCol, retentionDays)
-	result.SQL = sql
+	// TTL 未变化：跳过，保证重复执行是幂等的
+	if len(commands) == 0 {
+		result.Skipped = true
+		result.SkipReason = "TTL 未变化"
+		return result
+	}
+
+	result.SQL = e.generateTTLSQL(table.Database, table.Table, commands)
+	return e.Apply(ctx, result)
+}
+
+// Apply 执行结果中预先生成的 SQL（如 plan 文件中的语句）
+// 执行前同样记录原有 TTL，执行后记录 ALTER 是否成功，rollback 恢复执行成功或部分节点成功的表
+func (e *Executor) Apply(ctx context.Context, result ExecutionResult) ExecutionResult {
+	if e.dryRun || e.recorder == nil {
+		return e.run(ctx, result)
+	}
+
+	// 修改前记录原有 TTL，记录失败时不执行，保证所有修改都可以回滚
+	entry := state.Entry{
+		Database: result.Database,
+		Table:    result.Table,
+		OldTTL:   result.OldTTL,
+		NewTTL:   result.NewTTL,
+		Columns:  result.Columns,
+		SQL:      result.SQL,
+	}
+	index, err := e.recorder.Record(entry)
+	if err != nil {
+		result.Error = fmt.Errorf("failed to record previous TTL: %w", err)
+		return result
+	}
+
+	result = e.run(ctx, result)
+
+	// 只有 ALTER 成功后才会等待 mutation，因此 mutation 失败时 ALTER 仍视为已执行
+	status := state.StatusApplied
+	var alterErr error
+	if !result.Success && result.Mutation == nil {
+		status = state.StatusFailed
+		if result.Partial {
+			status = state.StatusPartial
+		}
+		alterErr = result.Error
+		if alterErr == nil {
+			alterErr = errors.New("TTL not applied")
+		}
+	}
+	if err := e.recorder.Finish(index, status, alterErr); err != nil {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("记录执行结果失败: %v", err))
+	}
+	return result
+}
+
+// RemoveScope remove 子命令移除的 TTL 范围
+type RemoveScope int
+
+const (
+	// RemoveTable 只移除表级 TTL，保留列级 TTL（如 PII 清理）
+	RemoveTable RemoveScope = iota
+	// RemoveColumns 只移除列级 TTL，保留表级 TTL
+	RemoveColumns
+	// RemoveAll 移除表级和所有列级 TTL
+	RemoveAll
+)
+
+// Remove 按 scope 移除表的 TTL（REMOVE TTL）和/或列级 TTL（MODIFY COLUMN ... REMOVE TTL）
+// 执行前同样记录原有 TTL，可通过 rollback 恢复
+func (e *Executor) Remove(ctx context.Context, table scanner.TableInfo, scope RemoveScope) ExecutionResult {
+	result := ExecutionResult{
+		Database:    table.Database,
+		Table:       table.Table,
+		Distributed: distributedName(table),
+		Checksum:    table.Checksum,
+		OldTTL:      table.CurrentTTL,
+		NewTTL:      table.CurrentTTL,
+		Change:      ChangeRemoved,
+	}
+
+	var commands []string
+	if scope != RemoveColumns && table.CurrentTTL != "" {
+		result.NewTTL = ""
+		commands = append(commands, "REMOVE TTL")
+	}
+
+	if scope != RemoveTable {
+		// 按列名排序，保证生成的语句稳定
+		columns := make([]string, 0, len(table.ColumnTTLs))
+		for c := range table.ColumnTTLs {
+			columns = append(columns, c)
+		}
+		sort.Strings(columns)
+		for _, c := range columns {
+			result.Columns = append(result.Columns, state.ColumnTTL{Column: c, OldTTL: table.ColumnTTLs[c]})
+			commands = append(commands, fmt.Sprintf("MODIFY COLUMN %s REMOVE TTL", utils.EscapeIdentifier(c)))
+		}
+	}
+
+	if len(commands) == 0 {
+		result.Change = ""
+		result.Skipped = true
+		switch scope {
+		case RemoveTable:
+			result.SkipReason = "没有表级 TTL"
+		case RemoveColumns:
+			result.SkipReason = "没有列级 TTL"
+		default:
+			result.SkipReason = "没有 TTL"
+		}
+		return result
+	}
 
+	result.SQL = e.generateTTLSQL(table.Database, table.Table, commands)
+	return e.Apply(ctx, result)
+}
+
+// Rollback 将表的 TTL 恢复为运行记录中的原有 TTL
+// 原本没有 TTL 的表执行 REMOVE TTL，列级 TTL 同理
+// current 为表当前的 TTL，与运行记录设置的 TTL 不一致（运行之后又被修改过）时拒绝回滚，force 为 true 时仍然覆盖
+func (e *Executor) Rollback(ctx context.Context, entry state.Entry, current scanner.TableInfo, force bool) ExecutionResult {
+	result := ExecutionResult{
+		Database: entry.Database,
+		Table:    entry.Table,
+		Checksum: current.Checksum,
+		OldTTL:   current.CurrentTTL,
+		NewTTL:   entry.OldTTL,
+	}
+
+	// 原操作没有在任何节点执行时表的 TTL 未被修改，回滚（尤其是 REMOVE TTL）会被服务端拒绝
+	if !entry.Applied() {
+		result.Skipped = true
+		result.SkipReason = fmt.Sprintf("原操作未执行成功（%s）", entry.Status)
+		if entry.Error != "" {
+			result.SkipReason = fmt.Sprintf("原操作未执行成功: %s", entry.Error)
+		}
+		return result
+	}
+
+	if conflicts := rollbackConflicts(entry, current); len(conflicts) > 0 {
+		if !force {
+			result.Error = fmt.Errorf("TTL changed after the run, refusing to overwrite (use --force): %s",
+				strings.Join(conflicts, "; "))
+			return result
+		}
+		result.Warnings = append(result.Warnings,
+			"TTL 在运行之后被修改过，--force 覆盖: "+strings.Join(conflicts, "; "))
+	}
+
+	var commands []string
+	if entry.OldTTL != entry.NewTTL {
+		if entry.OldTTL == "" {
+			commands = append(commands, "REMOVE TTL")
+		} else {
+			commands = append(commands, "MODIFY TTL "+entry.OldTTL)
+		}
+	}
+	for _, c := range entry.Columns {
+		col := utils.EscapeIdentifier(c.Column)
+		if c.OldTTL == "" {
+			commands = append(commands, fmt.Sprintf("MODIFY COLUMN %s REMOVE TTL", col))
+		} else {
+			commands = append(commands, fmt.Sprintf("MODIFY COLUMN %s TTL %s", col, c.OldTTL))
+		}
+		result.Columns = append(result.Columns, state.ColumnTTL{Column: c.Column, OldTTL: c.NewTTL, NewTTL: c.OldTTL})
+	}
+
+	if len(commands) == 0 {
+		result.Skipped = true
+		result.SkipReason = "没有需要回滚的 TTL"
+		return result
+	}
+
+	result.SQL = e.generateTTLSQL(entry.Database, entry.Table, commands)
+	return e.run(ctx, result)
+}
+
+// rollbackConflicts 比较表当前的 TTL 与运行记录设置的 TTL，返回不一致的描述
+// 部分节点执行的记录中，当前节点可能仍是原有 TTL，两者都视为一致
+func rollbackConflicts(entry state.Entry, current scanner.TableInfo) []string {
+	matches := func(currentTTL, newTTL, oldTTL string) bool {
+		if normalizeTTL(currentTTL) == normalizeTTL(newTTL) {
+			return true
+		}
+		return entry.Status == state.StatusPartial && normalizeTTL(currentTTL) == normalizeTTL(oldTTL)
+	}
+
+	var conflicts []string
+	if entry.OldTTL != entry.NewTTL && !matches(current.CurrentTTL, entry.NewTTL, entry.OldTTL) {
+		conflicts = append(conflicts, fmt.Sprintf("table TTL is %q, run set %q", current.CurrentTTL, entry.NewTTL))
+	}
+	for _, c := range entry.Columns {
+		if now := current.ColumnTTLs[c.Column]; !matches(now, c.NewTTL, c.OldTTL) {
+			conflicts = append(conflicts, fmt.Sprintf("column %s TTL is %q, run set %q", c.Column, now, c.NewTTL))
+		}
+	}
+	return conflicts
+}
+
+// Materialize 逐个分区执行 MATERIALIZE TTL，按新的 TTL 重写已有数据
+// 每个分区单独执行并经过限流，避免一次性重写整个表；某个分区失败时停止处理该表
+func (e *Executor) Materialize(ctx context.Context, table scanner.TableInfo, partitions []string) ExecutionResult {
+	result := ExecutionResult{
+		Database:    table.Database,
+		Table:       table.Table,
+		Distributed: distributedName(table),
+		Checksum:    table.Checksum,
+		OldTTL:      table.CurrentTTL,
+		NewTTL:      table.CurrentTTL,
+		Partitions:  partitions,
+	}
+
+	if table.CurrentTTL == "" && len(table.ColumnTTLs) == 0 {
+		result.Skipped = true
+		result.SkipReason = "没有 TTL"
+		return result
+	}
+	if len(partitions) == 0 {
+		result.Skipped = true
+		result.SkipReason = "没有数据分区"
+		return result
+	}
+
+	statements := make([]string, 0, len(partitions))
+	for _, p := range partitions {
+		statements = append(statements,
+			e.alterPrefix(table.Database, table.Table)+" MATERIALIZE TTL IN PARTITION ID "+utils.EscapeString(p))
+	}
+	result.SQL = strings.Join(statements, ";\n")
+
+	for i, stmt := range statements {
+		partial := result
+		partial.SQL = stmt
+		partial = e.run(ctx, partial)
+
+		result.Waited += partial.Waited
+		result.FailedHosts = partial.FailedHosts
+		if partial.Mutation != nil {
+			merged := *partial.Mutation
+			if result.Mutation != nil {
+				merged.IDs = append(result.Mutation.IDs, merged.IDs...)
+			}
+			result.Mutation = &merged
+		}
+		if partial.Error != nil {
+			result.Error = fmt.Errorf("partition %s: %w", partitions[i], partial.Error)
+			return result
+		}
+	}
+
+	result.Success = true
+	return result
+}
+
+// run 执行结果中的 SQL，Dry-Run 模式下不执行
+func (e *Executor) run(ctx context.Context, result ExecutionResult) ExecutionResult {
 	// Dry-Run 模式：仅记录 SQL，不执行
 	if e.dryRun {
 		result.Success = true
 		return result
 	}
 
-	// 执行 ALTER TABLE 语句
-	if err := e.client.Exec(ctx, sql); err != nil {
-		result.Error = fmt.Errorf("failed to execute TTL: %w", err)
+	// 服务端合并或 mutation 负载过高时先等待
+	if e.throttle != nil {
+		waited, err := e.throttle.Wait(ctx)
+		result.Waited = waited
+		if err != nil {
+			result.Error = fmt.Errorf("throttle: %w", err)
+			return result
+		}
+	}
+
+	submitted := time.Now()
+
+	if e.cluster != nil {
+		// ON CLUSTER 模式：提交后通过 system.distributed_ddl_queue 跟踪各节点
+		result = e.executeOnCluster(ctx, result, submitted)
+		if !result.Success {
+			return result
+		}
+	} else {
+		// 执行 ALTER TABLE 语句
+		if err := e.client.ExecWithSettings(ctx, e.settings(), result.SQL); err != nil {
+			result.Error = fmt.Errorf("failed to execute TTL: %w", err)
+			result.Success = false
+			return result
+		}
+		result.Success = true
+	}
+
+	// --wait 模式：等待 ALTER 产生的 mutation 完成
+	if e.mutations != nil && e.expectsMutation(result.SQL) {
+		result = e.waitMutation(ctx, result, submitted)
+	}
+
+	return result
+}
+
+// expectsMutation 判断语句是否会产生需要等待的 mutation
+// MATERIALIZE TTL 总是产生 mutation；修改 TTL 仅在物化已有数据时产生，REMOVE TTL 不产生
+func (e *Executor) expectsMutation(sql string) bool {
+	if strings.Contains(sql, " MATERIALIZE TTL") {
+		return true
+	}
+	if e.noMaterialize {
+		return false
+	}
+	return strings.Contains(sql, "MODIFY TTL ") || modifyColumnTTL.MatchString(sql)
+}
+
+// waitMutation 等待表在 submitted 之后提交的 mutation 完成，并记录最终状态
+func (e *Executor) waitMutation(ctx context.Context, result ExecutionResult, submitted time.Time) ExecutionResult {
+	status, err := e.mutations.Wait(ctx, result.Database, result.Table, submitted)
+	result.Mutation = &status
+
+	switch {
+	case err != nil:
+		result.Success = false
+		result.Error = fmt.Errorf("failed to wait for mutation: %w", err)
+	case status.FailReason != "":
 		result.Success = false
+		result.Error = fmt.Errorf("mutation %s failed: %s", strings.Join(status.IDs, ", "), status.FailReason)
+	}
+
+	return result
+}
+
+// SkippedResult 构建跳过的执行结果
+func SkippedResult(table scanner.TableInfo, reason string) ExecutionResult {
+	return ExecutionResult{
+		Database:    table.Database,
+		Table:       table.Table,
+		Distributed: distributedName(table),
+		Checksum:    table.Checksum,
+		Skipped:     true,
+		SkipReason:  reason,
+	}
+}
+
+// distributedName 返回表的来源分布式表名，非分布式解析得到的表返回空
+func distributedName(table scanner.TableInfo) string {
+	if table.Distributed == nil {
+		return ""
+	}
+	return table.Distributed.String()
+}
+
+// executeOnCluster 提交 ON CLUSTER 语句并等待所有节点完成
+// submitted 为提交语句的时间，用于排除更早的任务
+func (e *Executor) executeOnCluster(ctx context.Context, result ExecutionResult, submitted time.Time) ExecutionResult {
+	// 不等待服务端返回各节点状态，改为自行轮询，以便记录每个节点的结果
+	settings := e.settings()
+	settings["distributed_ddl_output_mode"] = "none"
+	if err := e.client.ExecWithSettings(ctx, settings, result.SQL); err != nil {
+		result.Error = fmt.Errorf("failed to execute TTL: %w", err)
+		return result
+	}
+
+	statuses, err := e.cluster.Wait(ctx, result.Database, result.Table, submitted)
+	if err != nil {
+		// 语句已提交，无法确认各节点是否执行，按部分执行处理，回滚前会核对当前 TTL
+		result.Error = fmt.Errorf("failed to track distributed DDL: %w", err)
+		result.Partial = true
+		return result
+	}
+
+	applied := false
+	for _, s := range statuses {
+		if s.Error != "" {
+			result.FailedHosts = append(result.FailedHosts, fmt.Sprintf("%s: %s", s.Host, s.Error))
+		}
+		// 执行成功或超时（可能稍后执行）的节点上 TTL 可能已被修改
+		if s.Error == "" || !s.Finished {
+			applied = true
+		}
+	}
+
+	if len(result.FailedHosts) > 0 {
+		result.Partial = applied
+		result.Error = fmt.Errorf("TTL failed on %d/%d hosts of cluster %s",
+			len(result.FailedHosts), len(statuses), e.cluster.Name())
 		return result
 	}
 
@@ -78,38 +553,181 @@ func (e *Executor) Execute(
 	return result
 }
 
-// generateTTLSQL 生成 TTL SQL 语句
+// settings 返回执行 ALTER 时使用的查询级别设置
+func (e *Executor) settings() map[string]interface{} {
+	settings := make(map[string]interface{})
+	if e.noMaterialize {
+		settings["materialize_ttl_after_modify"] = 0
+	}
+	return settings
+}
+
+// generateTTLSQL 生成 TTL SQL 语句，多个修改以逗号分隔合并为一条 ALTER
 // 使用标识符转义防止 SQL 注入
-func (e *Executor) generateTTLSQL(
-	database, table string,
-	col *detector.TimeColumn,
-	days int,
-) string {
+func (e *Executor) generateTTLSQL(database, table string, commands []string) string {
+	return e.alterPrefix(database, table) + " " + strings.Join(commands, ", ")
+}
+
+// generateTTLExpr 生成 TTL 表达式（MODIFY TTL 之后的部分）
+// 多条子句以逗号分隔，带条件的子句生成 DELETE WHERE，移动子句生成 TO DISK / TO VOLUME，
+// 聚合子句生成 [WHERE ...] GROUP BY ... [SET ...]，重新压缩子句生成 RECOMPRESS CODEC(...)
+// 子句按保留天数从小到大排列，天数相同时删除排在最后
+func generateTTLExpr(col *detector.TimeColumn, rules []policy.TTLRule) string {
+	base := timeExpr(col)
+
+	ordered := make([]policy.TTLRule, len(rules))
+	copy(ordered, rules)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if ordered[i].RetentionDays != ordered[j].RetentionDays {
+			return ordered[i].RetentionDays < ordered[j].RetentionDays
+		}
+		return !ordered[i].IsDelete() && ordered[j].IsDelete()
+	})
+
+	parts := make([]string, 0, len(ordered))
+	for _, r := range ordered {
+		expr := expiry(col, base, r.RetentionDays)
+		switch {
+		case r.ToDisk != "":
+			expr += " TO DISK " + utils.EscapeString(r.ToDisk)
+		case r.ToVolume != "":
+			expr += " TO VOLUME " + utils.EscapeString(r.ToVolume)
+		case r.IsRecompress():
+			expr += " RECOMPRESS CODEC(" + r.Recompress + ")"
+		case r.IsGroupBy():
+			if r.Where != "" {
+				expr += " WHERE " + r.Where
+			}
+			expr += " GROUP BY " + strings.Join(r.GroupBy, ", ")
+			if len(r.Set) > 0 {
+				assignments := make([]string, 0, len(r.Set))
+				for _, c := range r.SetColumns() {
+					assignments = append(assignments, utils.EscapeIdentifier(c)+" = "+r.Set[c])
+				}
+				expr += " SET " + strings.Join(assignments, ", ")
+			}
+		case r.Where != "":
+			expr += " DELETE WHERE " + r.Where
+		}
+		parts = append(parts, expr)
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// neverExpire 时间为 NULL 时使用的过期时间（DateTime 的最大值 2106-02-07），这些行不会过期
+// 在加上保留天数之后兜底，避免 DateTime 加法溢出后回绕到 1970 年
+const neverExpire = "toDateTime(4294967295)"
+
+// expiry 生成单条子句的过期时间表达式 <时间> + INTERVAL N DAY
+// 时间可能为 NULL 时用 ifNull 兜底为 neverExpire，TTL 表达式的结果不能是 Nullable
+func expiry(col *detector.TimeColumn, base string, days int) string {
+	expr := fmt.Sprintf("%s + INTERVAL %d DAY", base, days)
+	if mayBeNull(col) {
+		return fmt.Sprintf("ifNull(%s, %s)", expr, neverExpire)
+	}
+	return expr
+}
+
+// mayBeNull 判断时间表达式是否可能为 NULL（Nullable 或文本字段，且策略未开启 expire_invalid_time）
+func mayBeNull(col *detector.TimeColumn) bool {
+	return (col.Nullable || col.IsString) && !col.ExpireInvalid
+}
+
+// timeExpr 生成 TTL 中使用的时间表达式，将时间字段转换为 TTL 支持的类型
+func timeExpr(col *detector.TimeColumn) string {
 	// 转义所有标识符
-	dbEscaped := utils.EscapeIdentifier(database)
-	tableEscaped := utils.EscapeIdentifier(table)
 	colEscaped := utils.EscapeIdentifier(col.Name)
 
-	// UInt64 纳秒时间戳：需要转换为 DateTime（除以 10^9 转为秒）
-	// fromUnixTimestamp64Nano 返回 DateTime64(9)，TTL 不支持，所以用除法+toDateTime
-	if col.IsUInt64 {
-		return fmt.Sprintf(
-			"ALTER TABLE %s.%s MODIFY TTL toDateTime(%s / 1000000000) + INTERVAL %d DAY",
-			dbEscaped, tableEscaped, colEscaped, days,
-		)
+	// Nullable 类型：开启 expire_invalid_time 时 NULL 按类型默认值（1970-01-01 / 0）处理，这些行立即过期；
+	// 否则保留 NULL，由 expiry 兜底为不过期
+	if col.Nullable && col.ExpireInvalid {
+		colEscaped = fmt.Sprintf("assumeNotNull(%s)", colEscaped)
+	}
+
+	// 文本时间：逐行解析，不使用会抛出异常的版本，避免后台合并失败；
+	// 开启 expire_invalid_time 时无法解析的值按 1970-01-01 处理（立即过期），否则为 NULL，由 expiry 兜底为不过期
+	if col.IsString {
+		if col.ExpireInvalid {
+			return detector.ParseTimeExpr(colEscaped, col.Format, "OrZero")
+		}
+		return detector.ParseTimeExpr(colEscaped, col.Format, "OrNull")
+	}
+
+	// 整数时间戳：按精度转换为秒后 toDateTime
+	switch col.Precision {
+	case detector.PrecisionSeconds:
+		return fmt.Sprintf("toDateTime(%s)", colEscaped)
+	case detector.PrecisionMillis, detector.PrecisionMicros:
+		return fmt.Sprintf("toDateTime(intDiv(%s, %d))", colEscaped, col.Precision.Divisor())
+	case detector.PrecisionNanos:
+		// fromUnixTimestamp64Nano 返回 DateTime64(9)，TTL 不支持，所以用除法+toDateTime
+		// 保持早期版本生成的表达式，已设置 TTL 的表重复执行时仍判定为未变化
+		return fmt.Sprintf("toDateTime(%s / %d)", colEscaped, col.Precision.Divisor())
 	}
 
-	// DateTime64 类型：需要转换为 DateTime（TTL 表达式不支持 DateTime64）
-	if strings.HasPrefix(col.Type, "DateTime64") {
-		return fmt.Sprintf(
-			"ALTER TABLE %s.%s MODIFY TTL toDateTime(%s) + INTERVAL %d DAY",
-			dbEscaped, tableEscaped, colEscaped, days,
-		)
+	// DateTime64 / Date32 类型：TTL 表达式的结果只能是 Date 或 DateTime，需要转换
+	t := chtype.Parse(col.Type)
+	if t.IsDateTime64() {
+		return fmt.Sprintf("toDateTime(%s)", colEscaped)
+	}
+	if t.IsDate32() {
+		return fmt.Sprintf("toDate(%s)", colEscaped)
 	}
 
 	// DateTime/Date 类型：直接使用
-	return fmt.Sprintf(
-		"ALTER TABLE %s.%s MODIFY TTL %s + INTERVAL %d DAY",
-		dbEscaped, tableEscaped, colEscaped, days,
-	)
+	return colEscaped
+}
+
+// timeWarnings 返回时间字段在 TTL 中的使用需要注意的问题
+func timeWarnings(col *detector.TimeColumn) []string {
+	var warnings []string
+	switch {
+	case col.Nullable && col.ExpireInvalid:
+		warnings = append(warnings, fmt.Sprintf(
+			"时间字段 %s 为 Nullable，策略开启了 expire_invalid_time，NULL 值按 1970-01-01 处理，这些行会立即过期",
+			col.Name))
+	case col.Nullable:
+		warnings = append(warnings, fmt.Sprintf(
+			"时间字段 %s 为 Nullable，NULL 值的行不会过期（如需删除，在策略规则中设置 expire_invalid_time: true）",
+			col.Name))
+	}
+	if col.IsString {
+		invalid := "无法解析的值不会过期"
+		if col.ExpireInvalid {
+			invalid = "策略开启了 expire_invalid_time，无法解析的值按 1970-01-01 处理，这些行会立即过期"
+		}
+		warnings = append(warnings, fmt.Sprintf(
+			"时间字段 %s 为文本，TTL 需要在每次合并时逐行解析，CPU 开销较大；%s", col.Name, invalid))
+	}
+	return warnings
+}
+
+// timeType 返回时间字段类型的描述，整数时间戳附带精度，如 UInt64 (ms)
+func timeType(col *detector.TimeColumn) string {
+	if col.Precision == detector.PrecisionNone {
+		return col.Type
+	}
+	return fmt.Sprintf("%s (%s)", col.Type, col.Precision)
+}
+
+// maxRetention 返回删除子句中最长的保留天数，没有删除子句时为 0
+func maxRetention(rules []policy.TTLRule) int {
+	days := 0
+	for _, r := range rules {
+		if r.IsDelete() && r.RetentionDays > days {
+			days = r.RetentionDays
+		}
+	}
+	return days
+}
+
+// alterPrefix 生成 ALTER TABLE 语句前缀，指定集群时附加 ON CLUSTER
+func (e *Executor) alterPrefix(database, table string) string {
+	prefix := fmt.Sprintf("ALTER TABLE %s.%s",
+		utils.EscapeIdentifier(database), utils.EscapeIdentifier(table))
+	if e.cluster != nil {
+		prefix += " ON CLUSTER " + utils.EscapeIdentifier(e.cluster.Name())
+	}
+	return prefix
 }
@@ -0,0 +1,201 @@
+package executor
+
+import (
+	"context"
+	"testing"
+
+	"clickhouse-ttl-tool/pkg/detector"
+	"clickhouse-ttl-tool/pkg/policy"
+	"clickhouse-ttl-tool/pkg/scanner"
+)
+
+func TestGenerateTTLExpr(t *testing.T) {
+	deleteAfter := func(days int) []policy.TTLRule {
+		return []policy.TTLRule{{RetentionDays: days}}
+	}
+
+	tests := []struct {
+		name  string
+		col   detector.TimeColumn
+		rules []policy.TTLRule
+		want  string
+	}{
+		{
+			name:  "datetime",
+			col:   detector.TimeColumn{Name: "event_time", Type: "DateTime"},
+			rules: deleteAfter(30),
+			want:  "`event_time` + INTERVAL 30 DAY",
+		},
+		{
+			name:  "datetime64",
+			col:   detector.TimeColumn{Name: "ts", Type: "DateTime64(3, 'UTC')"},
+			rules: deleteAfter(7),
+			want:  "toDateTime(`ts`) + INTERVAL 7 DAY",
+		},
+		{
+			name:  "date32",
+			col:   detector.TimeColumn{Name: "d", Type: "Date32"},
+			rules: deleteAfter(7),
+			want:  "toDate(`d`) + INTERVAL 7 DAY",
+		},
+		{
+			name:  "milliseconds",
+			col:   detector.TimeColumn{Name: "ts", Type: "UInt64", Precision: detector.PrecisionMillis},
+			rules: deleteAfter(7),
+			want:  "toDateTime(intDiv(`ts`, 1000)) + INTERVAL 7 DAY",
+		},
+		{
+			name:  "nanoseconds",
+			col:   detector.TimeColumn{Name: "ts", Type: "Int64", Precision: detector.PrecisionNanos},
+			rules: deleteAfter(7),
+			want:  "toDateTime(`ts` / 1000000000) + INTERVAL 7 DAY",
+		},
+		{
+			name:  "nullable keeps null rows",
+			col:   detector.TimeColumn{Name: "ts", Type: "Nullable(DateTime)", Nullable: true},
+			rules: deleteAfter(30),
+			want:  "ifNull(`ts` + INTERVAL 30 DAY, toDateTime(4294967295))",
+		},
+		{
+			name:  "nullable expires null rows",
+			col:   detector.TimeColumn{Name: "ts", Type: "Nullable(DateTime)", Nullable: true, ExpireInvalid: true},
+			rules: deleteAfter(30),
+			want:  "assumeNotNull(`ts`) + INTERVAL 30 DAY",
+		},
+		{
+			name:  "text keeps unparseable rows",
+			col:   detector.TimeColumn{Name: "log_time", Type: "String", IsString: true},
+			rules: deleteAfter(30),
+			want:  "ifNull(parseDateTimeBestEffortOrNull(`log_time`) + INTERVAL 30 DAY, toDateTime(4294967295))",
+		},
+		{
+			name: "nullable text with format expires unparseable rows",
+			col: detector.TimeColumn{Name: "log_time", Type: "Nullable(String)", Nullable: true,
+				IsString: true, Format: "%Y%m%d", ExpireInvalid: true},
+			rules: deleteAfter(30),
+			want:  "parseDateTimeOrZero(assumeNotNull(`log_time`), '%Y%m%d') + INTERVAL 30 DAY",
+		},
+		{
+			name: "delete where before final delete",
+			col:  detector.TimeColumn{Name: "ts", Type: "DateTime"},
+			rules: []policy.TTLRule{
+				{RetentionDays: 90},
+				{RetentionDays: 3, Where: "level = 'debug'"},
+			},
+			want: "`ts` + INTERVAL 3 DAY DELETE WHERE level = 'debug', `ts` + INTERVAL 90 DAY",
+		},
+		{
+			name: "move to disk and volume",
+			col:  detector.TimeColumn{Name: "ts", Type: "DateTime"},
+			rules: []policy.TTLRule{
+				{RetentionDays: 7, ToDisk: "cold"},
+				{RetentionDays: 30, ToVolume: "archive"},
+				{RetentionDays: 90},
+			},
+			want: "`ts` + INTERVAL 7 DAY TO DISK 'cold', `ts` + INTERVAL 30 DAY TO VOLUME 'archive', `ts` + INTERVAL 90 DAY",
+		},
+		{
+			name: "group by with set",
+			col:  detector.TimeColumn{Name: "ts", Type: "DateTime"},
+			rules: []policy.TTLRule{{
+				RetentionDays: 30,
+				Where:         "kind = 'metric'",
+				GroupBy:       []string{"tenant_id", "toStartOfDay(ts)"},
+				Set:           map[string]string{"value": "sum(value)", "hits": "max(hits)"},
+			}},
+			want: "`ts` + INTERVAL 30 DAY WHERE kind = 'metric' GROUP BY tenant_id, toStartOfDay(ts) " +
+				"SET `hits` = max(hits), `value` = sum(value)",
+		},
+		{
+			name: "clauses ordered by days, delete last",
+			col:  detector.TimeColumn{Name: "ts", Type: "DateTime"},
+			rules: []policy.TTLRule{
+				{RetentionDays: 90},
+				{RetentionDays: 3, Where: "level = 'debug'"},
+				{RetentionDays: 90, ToVolume: "archive"},
+				{RetentionDays: 7, ToDisk: "cold"},
+				{RetentionDays: 30, Recompress: "ZSTD(17)"},
+			},
+			want: "`ts` + INTERVAL 3 DAY DELETE WHERE level = 'debug', " +
+				"`ts` + INTERVAL 7 DAY TO DISK 'cold', " +
+				"`ts` + INTERVAL 30 DAY RECOMPRESS CODEC(ZSTD(17)), " +
+				"`ts` + INTERVAL 90 DAY TO VOLUME 'archive', " +
+				"`ts` + INTERVAL 90 DAY",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := generateTTLExpr(&tt.col, tt.rules); got != tt.want {
+				t.Errorf("generateTTLExpr() =\n %q\nwant\n %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemove(t *testing.T) {
+	table := scanner.TableInfo{
+		Database:   "logs",
+		Table:      "events",
+		CurrentTTL: "event_time + toIntervalDay(90)",
+		ColumnTTLs: map[string]string{
+			"user_agent": "event_time + toIntervalDay(30)",
+			"ip":         "event_time + toIntervalDay(30)",
+		},
+	}
+	columnsOnly := scanner.TableInfo{Database: "logs", Table: "users", ColumnTTLs: table.ColumnTTLs}
+
+	tests := []struct {
+		name    string
+		table   scanner.TableInfo
+		scope   RemoveScope
+		wantSQL string
+		columns int
+	}{
+		{
+			name:    "table ttl only by default",
+			table:   table,
+			scope:   RemoveTable,
+			wantSQL: "ALTER TABLE `logs`.`events` REMOVE TTL",
+		},
+		{
+			name:    "columns only",
+			table:   table,
+			scope:   RemoveColumns,
+			wantSQL: "ALTER TABLE `logs`.`events` MODIFY COLUMN `ip` REMOVE TTL, MODIFY COLUMN `user_agent` REMOVE TTL",
+			columns: 2,
+		},
+		{
+			name:  "all",
+			table: table,
+			scope: RemoveAll,
+			wantSQL: "ALTER TABLE `logs`.`events` REMOVE TTL, " +
+				"MODIFY COLUMN `ip` REMOVE TTL, MODIFY COLUMN `user_agent` REMOVE TTL",
+			columns: 2,
+		},
+		{
+			name:  "keeps column ttls when the table has none",
+			table: columnsOnly,
+			scope: RemoveTable,
+		},
+	}
+
+	exec := NewExecutor(nil, Options{DryRun: true})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := exec.Remove(context.Background(), tt.table, tt.scope)
+			if tt.wantSQL == "" {
+				if !result.Skipped {
+					t.Fatalf("Remove() SQL = %q, want skipped", result.SQL)
+				}
+				return
+			}
+			if result.SQL != tt.wantSQL {
+				t.Errorf("Remove() SQL = %q, want %q", result.SQL, tt.wantSQL)
+			}
+			if len(result.Columns) != tt.columns {
+				t.Errorf("Remove() recorded %d columns, want %d", len(result.Columns), tt.columns)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package executor
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"clickhouse-ttl-tool/pkg/scanner"
+	"clickhouse-ttl-tool/pkg/state"
+)
+
+func TestRollback(t *testing.T) {
+	modified := state.Entry{
+		Database: "logs",
+		Table:    "events",
+		OldTTL:   "ts + toIntervalDay(90)",
+		NewTTL:   "`ts` + INTERVAL 30 DAY",
+		Columns:  []state.ColumnTTL{{Column: "ip", NewTTL: "`ts` + INTERVAL 7 DAY"}},
+		Status:   state.StatusApplied,
+	}
+	added := state.Entry{Database: "logs", Table: "events", NewTTL: "`ts` + INTERVAL 30 DAY", Status: state.StatusPartial}
+
+	// 服务端保存的是格式化后的 TTL
+	afterRun := scanner.TableInfo{
+		CurrentTTL: "ts + toIntervalDay(30)",
+		ColumnTTLs: map[string]string{"ip": "ts + toIntervalDay(7)"},
+	}
+	changedLater := scanner.TableInfo{
+		CurrentTTL: "ts + toIntervalDay(60)",
+		ColumnTTLs: map[string]string{"ip": "ts + toIntervalDay(7)"},
+	}
+
+	tests := []struct {
+		name    string
+		entry   state.Entry
+		current scanner.TableInfo
+		force   bool
+		wantSQL string
+		wantErr string
+		skipped bool
+	}{
+		{
+			name:    "restore table and column ttl",
+			entry:   modified,
+			current: afterRun,
+			wantSQL: "ALTER TABLE `logs`.`events` MODIFY TTL ts + toIntervalDay(90), MODIFY COLUMN `ip` REMOVE TTL",
+		},
+		{
+			name:    "changed after run",
+			entry:   modified,
+			current: changedLater,
+			wantErr: "table TTL is",
+		},
+		{
+			name:    "changed after run with force",
+			entry:   modified,
+			current: changedLater,
+			force:   true,
+			wantSQL: "ALTER TABLE `logs`.`events` MODIFY TTL ts + toIntervalDay(90), MODIFY COLUMN `ip` REMOVE TTL",
+		},
+		{
+			name:    "column changed after run",
+			entry:   modified,
+			current: scanner.TableInfo{CurrentTTL: "ts + toIntervalDay(30)"},
+			wantErr: "column ip TTL is",
+		},
+		{
+			name:    "partial run applied on this node",
+			entry:   added,
+			current: scanner.TableInfo{CurrentTTL: "ts + toIntervalDay(30)"},
+			wantSQL: "ALTER TABLE `logs`.`events` REMOVE TTL",
+		},
+		{
+			name:    "partial run not applied on this node",
+			entry:   added,
+			current: scanner.TableInfo{},
+			wantSQL: "ALTER TABLE `logs`.`events` REMOVE TTL",
+		},
+		{
+			name:    "failed run",
+			entry:   state.Entry{Database: "logs", Table: "events", NewTTL: "x", Status: state.StatusFailed, Error: "code 36"},
+			skipped: true,
+		},
+	}
+
+	exec := NewExecutor(nil, Options{DryRun: true})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := exec.Rollback(context.Background(), tt.entry, tt.current, tt.force)
+			switch {
+			case tt.skipped:
+				if !result.Skipped {
+					t.Errorf("Rollback() skipped = false, want true")
+				}
+			case tt.wantErr != "":
+				if result.Error == nil || !strings.Contains(result.Error.Error(), tt.wantErr) {
+					t.Errorf("Rollback() error = %v, want %q", result.Error, tt.wantErr)
+				}
+				if result.SQL != "" {
+					t.Errorf("Rollback() SQL = %q, want none", result.SQL)
+				}
+			default:
+				if result.Error != nil {
+					t.Fatalf("Rollback() error = %v", result.Error)
+				}
+				if result.SQL != tt.wantSQL {
+					t.Errorf("Rollback() SQL = %q, want %q", result.SQL, tt.wantSQL)
+				}
+				if tt.force && len(result.Warnings) == 0 {
+					t.Errorf("Rollback() with force should warn about the overwritten TTL")
+				}
+			}
+		})
+	}
+}
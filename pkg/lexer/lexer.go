@@ -0,0 +1,123 @@
+// 使用方法：ClickHouse 表达式的词法分析，供提取表达式引用的列名使用
+// 识别标识符、带引号的标识符、字符串字面量、数字和符号，不做语法分析
+package lexer
+
+import (
+	"errors"
+	"strings"
+)
+
+// Kind 词法单元类型
+type Kind int
+
+const (
+	// Ident 未加引号的标识符，可包含点号（嵌套列，如 attrs.key）
+	Ident Kind = iota
+	// QuotedIdent 反引号或双引号包围的标识符
+	QuotedIdent
+	// String 单引号包围的字符串字面量
+	String
+	// Number 数字字面量（包括 1.5e3、0x1F 等形式）
+	Number
+	// Symbol 运算符、括号、逗号等其他符号，-> 作为一个整体
+	Symbol
+)
+
+// Token 词法单元
+type Token struct {
+	Kind  Kind
+	Value string // 标识符和字符串为去掉引号和转义后的内容，其余为原文
+	Pos   int    // 在表达式中的起始位置
+}
+
+// Is 判断是否为指定的符号
+func (t Token) Is(symbol string) bool {
+	return t.Kind == Symbol && t.Value == symbol
+}
+
+// IsIdent 判断是否为标识符（带或不带引号）
+func (t Token) IsIdent() bool {
+	return t.Kind == Ident || t.Kind == QuotedIdent
+}
+
+// Tokenize 将表达式拆分为词法单元，跳过空白
+// 引号内支持反斜杠转义和连续两个引号的转义；引号未闭合时返回已识别的单元和错误
+func Tokenize(expr string) ([]Token, error) {
+	var tokens []Token
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+
+		case c == '\'' || c == '`' || c == '"':
+			value, end, err := readQuoted(expr, i)
+			if err != nil {
+				return tokens, err
+			}
+			kind := QuotedIdent
+			if c == '\'' {
+				kind = String
+			}
+			tokens = append(tokens, Token{Kind: kind, Value: value, Pos: i})
+			i = end
+
+		case c >= '0' && c <= '9':
+			start := i
+			for i < len(expr) && (isWordChar(expr[i]) || expr[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, Token{Kind: Number, Value: expr[start:i], Pos: start})
+
+		case isWordChar(c):
+			start := i
+			for i < len(expr) && (isWordChar(expr[i]) || expr[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, Token{Kind: Ident, Value: expr[start:i], Pos: start})
+
+		case c == '-' && i+1 < len(expr) && expr[i+1] == '>':
+			tokens = append(tokens, Token{Kind: Symbol, Value: "->", Pos: i})
+			i += 2
+
+		default:
+			tokens = append(tokens, Token{Kind: Symbol, Value: string(c), Pos: i})
+			i++
+		}
+	}
+	return tokens, nil
+}
+
+// IsCall 判断 tokens[i] 是否为函数名（后面紧跟左括号）
+func IsCall(tokens []Token, i int) bool {
+	return i+1 < len(tokens) && tokens[i+1].Is("(")
+}
+
+// readQuoted 读取从 start 开始的引号内容，返回去掉引号和转义后的内容及结束引号之后的位置
+func readQuoted(s string, start int) (string, int, error) {
+	quote := s[start]
+	var b strings.Builder
+	for i := start + 1; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s):
+			i++
+			b.WriteByte(s[i])
+		case s[i] == quote && i+1 < len(s) && s[i+1] == quote:
+			i++
+			b.WriteByte(quote)
+		case s[i] == quote:
+			return b.String(), i + 1, nil
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String(), len(s), errors.New("unterminated quoted string")
+}
+
+// isWordChar 判断是否为标识符字符
+func isWordChar(c byte) bool {
+	return c == '_' ||
+		(c >= 'a' && c <= 'z') ||
+		(c >= 'A' && c <= 'Z') ||
+		(c >= '0' && c <= '9')
+}
@@ -0,0 +1,69 @@
+package lexer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		expr string
+		want []Token
+	}{
+		{
+			expr: "toYYYYMM(event_time)",
+			want: []Token{
+				{Ident, "toYYYYMM", 0}, {Symbol, "(", 8}, {Ident, "event_time", 9}, {Symbol, ")", 19},
+			},
+		},
+		{
+			expr: "`a``b` = 'it\\'s' AND \"c\\\"d\" != 'x''y'",
+			want: []Token{
+				{QuotedIdent, "a`b", 0}, {Symbol, "=", 7}, {String, "it's", 9}, {Ident, "AND", 17},
+				{QuotedIdent, `c"d`, 21}, {Symbol, "!", 28}, {Symbol, "=", 29}, {String, "x'y", 31},
+			},
+		},
+		{
+			expr: "arrayExists(x -> x > 1.5e3, attrs.key)",
+			want: []Token{
+				{Ident, "arrayExists", 0}, {Symbol, "(", 11}, {Ident, "x", 12}, {Symbol, "->", 14},
+				{Ident, "x", 17}, {Symbol, ">", 19}, {Number, "1.5e3", 21}, {Symbol, ",", 26},
+				{Ident, "attrs.key", 28}, {Symbol, ")", 37},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := Tokenize(tt.expr)
+			if err != nil {
+				t.Fatalf("Tokenize() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Tokenize() =\n %v\nwant\n %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenizeUnterminated(t *testing.T) {
+	for _, expr := range []string{"level = 'debug", "`col", "'trailing\\"} {
+		tokens, err := Tokenize(expr)
+		if err == nil {
+			t.Errorf("Tokenize(%q) error = nil, want error", expr)
+		}
+		if expr == "level = 'debug" && len(tokens) != 2 {
+			t.Errorf("Tokenize(%q) = %v, want the tokens before the quote", expr, tokens)
+		}
+	}
+}
+
+func TestIsCall(t *testing.T) {
+	tokens, _ := Tokenize("f (x), y")
+	want := []bool{true, false, false, false, false, false}
+	for i := range tokens {
+		if got := IsCall(tokens, i); got != want[i] {
+			t.Errorf("IsCall(%q) = %v, want %v", tokens[i].Value, got, want[i])
+		}
+	}
+}
@@ -0,0 +1,147 @@
+// 使用方法：表名/库名匹配模式
+// 支持精确匹配、glob 通配符和正则表达式三种方式
+package matcher
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Kind 匹配方式
+type Kind int
+
+const (
+	// KindExact 精确匹配
+	KindExact Kind = iota
+	// KindGlob glob 通配符匹配（*、?、[...]）
+	KindGlob
+	// KindRegex 正则表达式匹配
+	KindRegex
+)
+
+// regexPrefix 命令行参数中表示正则表达式的前缀
+const regexPrefix = "re:"
+
+// Pattern 匹配模式
+type Pattern struct {
+	Kind  Kind   // 匹配方式
+	Value string // 原始模式字符串
+	re    *regexp.Regexp
+}
+
+// Exact 创建精确匹配模式
+func Exact(value string) Pattern {
+	return Pattern{Kind: KindExact, Value: value}
+}
+
+// Glob 创建 glob 通配符匹配模式
+func Glob(value string) (Pattern, error) {
+	if _, err := path.Match(value, ""); err != nil {
+		return Pattern{}, fmt.Errorf("invalid glob pattern %q: %w", value, err)
+	}
+	return Pattern{Kind: KindGlob, Value: value}, nil
+}
+
+// Regex 创建正则表达式匹配模式
+func Regex(value string) (Pattern, error) {
+	re, err := regexp.Compile(value)
+	if err != nil {
+		return Pattern{}, fmt.Errorf("invalid regex pattern %q: %w", value, err)
+	}
+	return Pattern{Kind: KindRegex, Value: value, re: re}, nil
+}
+
+// Parse 解析命令行形式的模式字符串
+//
+// 规则:
+//
+//	"re:^tmp_.*$" -> 正则表达式
+//	"^ts$"        -> 正则表达式（以 ^ 开头或以 $ 结尾，可省略 re: 前缀）
+//	"debug_*"     -> glob（包含 * ? [ 任一字符）
+//	"audit_log"   -> 精确匹配
+func Parse(value string) (Pattern, error) {
+	if strings.HasPrefix(value, regexPrefix) {
+		return Regex(strings.TrimPrefix(value, regexPrefix))
+	}
+	if isAnchored(value) {
+		return Regex(value)
+	}
+	if strings.ContainsAny(value, "*?[") {
+		return Glob(value)
+	}
+	return Exact(value), nil
+}
+
+// isAnchored 判断未加前缀的值是否带有正则锚点，这类值按正则表达式处理
+// ^、$ 在 glob 和库表列名中几乎不会出现；.* 仍是合法的 glob（如 my_db.*），不作判断
+func isAnchored(value string) bool {
+	return strings.HasPrefix(value, "^") || strings.HasSuffix(value, "$")
+}
+
+// ParseAll 批量解析模式字符串
+func ParseAll(values []string) ([]Pattern, error) {
+	patterns := make([]Pattern, 0, len(values))
+	for _, v := range values {
+		p, err := Parse(v)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, p)
+	}
+	return patterns, nil
+}
+
+// SplitList 将逗号分隔的模式列表拆分为单个模式，忽略空项
+// re: 前缀的正则整体保留不拆分，避免 {1,3} 等量词中的逗号被误拆
+func SplitList(values []string) []string {
+	var out []string
+	for _, v := range values {
+		if strings.HasPrefix(v, regexPrefix) {
+			out = append(out, v)
+			continue
+		}
+		for _, part := range strings.Split(v, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				out = append(out, part)
+			}
+		}
+	}
+	return out
+}
+
+// Match 判断名称是否匹配该模式
+func (p Pattern) Match(name string) bool {
+	switch p.Kind {
+	case KindGlob:
+		ok, _ := path.Match(p.Value, name)
+		return ok
+	case KindRegex:
+		return p.re != nil && p.re.MatchString(name)
+	default:
+		return p.Value == name
+	}
+}
+
+// String 返回模式的可读描述
+func (p Pattern) String() string {
+	switch p.Kind {
+	case KindGlob:
+		return "glob:" + p.Value
+	case KindRegex:
+		return "regex:" + p.Value
+	default:
+		return p.Value
+	}
+}
+
+// MatchAny 判断名称是否匹配任一模式
+func MatchAny(patterns []Pattern, name string) bool {
+	for _, p := range patterns {
+		if p.Match(name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package matcher
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		value   string
+		kind    Kind
+		match   []string
+		noMatch []string
+	}{
+		{"audit_log", KindExact, []string{"audit_log"}, []string{"audit_log_2024"}},
+		{"log_*", KindGlob, []string{"log_app", "log_"}, []string{"app_log"}},
+		{"my_db.*", KindGlob, []string{"my_db.events"}, []string{"other.events"}},
+		{"re:_(archive|forever)$", KindRegex, []string{"log_archive", "x_forever"}, []string{"archive_log"}},
+		{"re:^logs_[0-9]{1,3}$", KindRegex, []string{"logs_1", "logs_123"}, []string{"logs_1234"}},
+		{"^ts$", KindRegex, []string{"ts"}, []string{"event_ts", "ts_ms"}},
+		{".*_at$", KindRegex, []string{"created_at"}, []string{"created_at_ms"}},
+		{"^tmp_", KindRegex, []string{"tmp_events"}, []string{"events_tmp_1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			p, err := Parse(tt.value)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if p.Kind != tt.kind {
+				t.Errorf("Parse().Kind = %v, want %v", p.Kind, tt.kind)
+			}
+			for _, name := range tt.match {
+				if !p.Match(name) {
+					t.Errorf("%s should match %q", p, name)
+				}
+			}
+			for _, name := range tt.noMatch {
+				if p.Match(name) {
+					t.Errorf("%s should not match %q", p, name)
+				}
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string // 错误信息中应包含的内容
+	}{
+		{"re:(", "invalid regex"},
+		{"log_[", "invalid glob"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			_, err := Parse(tt.value)
+			if err == nil {
+				t.Fatalf("Parse(%q) error = nil, want error", tt.value)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Parse(%q) error = %q, want it to contain %q", tt.value, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitList(t *testing.T) {
+	tests := []struct {
+		values []string
+		want   []string
+	}{
+		{[]string{"db1,db2", "db3"}, []string{"db1", "db2", "db3"}},
+		{[]string{" db1 , ,db2 "}, []string{"db1", "db2"}},
+		{[]string{"re:^logs_[0-9]{1,3}$", "db1"}, []string{"re:^logs_[0-9]{1,3}$", "db1"}},
+		{nil, nil},
+	}
+
+	for _, tt := range tests {
+		if got := SplitList(tt.values); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SplitList(%q) = %q, want %q", tt.values, got, tt.want)
+		}
+	}
+}
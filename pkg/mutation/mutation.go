@@ -0,0 +1,191 @@
+// 使用方法：等待 ALTER 产生的 TTL mutation 执行完成
+// 通过 system.mutations 轮询 is_done、parts_to_do 和 latest_fail_reason
+package mutation
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"clickhouse-ttl-tool/pkg/client"
+	"clickhouse-ttl-tool/pkg/utils"
+)
+
+// pollInterval 轮询 system.mutations 的间隔
+const pollInterval = 2 * time.Second
+
+// Status mutation 执行状态
+type Status struct {
+	IDs        []string // 匹配的 mutation ID，集群模式下可能来自多个节点
+	PartsToDo  int64    // 剩余待处理的 part 数
+	Done       bool     // 是否全部完成
+	FailReason string   // 最近一次失败原因，没有失败时为空
+}
+
+// State 返回状态描述：none / running / done / failed
+func (s Status) State() string {
+	switch {
+	case len(s.IDs) == 0:
+		return "none"
+	case s.FailReason != "":
+		return "failed"
+	case s.Done:
+		return "done"
+	default:
+		return "running"
+	}
+}
+
+// Watcher mutation 跟踪器
+type Watcher struct {
+	client  *client.Client
+	cluster string
+	timeout time.Duration
+	// 复制表或 ON CLUSTER 时等待 mutation 出现的最长时间
+	lookup   time.Duration
+	progress func(database, table string, status Status)
+}
+
+// NewWatcher 创建 mutation 跟踪器
+// cluster 非空时通过 clusterAllReplicas 查询集群所有节点的 mutation
+// lookup 为提交后等待 mutation 出现的最长时间，仅复制表和 ON CLUSTER 需要等待（副本从队列拉取后才出现）
+// progress 在剩余 part 数变化时调用，可以为 nil
+func NewWatcher(
+	client *client.Client,
+	cluster string,
+	timeout time.Duration,
+	lookup time.Duration,
+	progress func(database, table string, status Status),
+) *Watcher {
+	return &Watcher{
+		client:   client,
+		cluster:  cluster,
+		timeout:  timeout,
+		lookup:   lookup,
+		progress: progress,
+	}
+}
+
+// Wait 等待指定表在 since 之后提交的 TTL mutation 完成或失败
+// 没有出现 mutation 时返回 none 状态：非复制表的 mutation 在 ALTER 返回前已经登记，首次查询没有即返回，
+// 复制表和 ON CLUSTER 最多等待 lookup；超时未完成时返回错误
+func (w *Watcher) Wait(ctx context.Context, database, table string, since time.Time) (Status, error) {
+	start := time.Now()
+	lastParts := int64(-1)
+	lookup := time.Duration(-1) // 首次没有找到 mutation 时确定
+
+	for {
+		status, err := w.poll(ctx, database, table, since)
+		if err != nil {
+			return status, err
+		}
+
+		switch status.State() {
+		case "none":
+			if lookup < 0 {
+				if lookup, err = w.lookupTimeout(ctx, database, table); err != nil {
+					return status, err
+				}
+			}
+			if time.Since(start) >= lookup {
+				return status, nil
+			}
+		case "done", "failed":
+			return status, nil
+		default:
+			if status.PartsToDo != lastParts && w.progress != nil {
+				w.progress(database, table, status)
+			}
+			lastParts = status.PartsToDo
+		}
+
+		if time.Since(start) >= w.timeout {
+			return status, fmt.Errorf("mutation not finished after %s, %d parts to do", w.timeout, status.PartsToDo)
+		}
+
+		select {
+		case <-ctx.Done():
+			return status, ctx.Err()
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// lookupTimeout 返回等待 mutation 出现的最长时间
+// 非复制表的 ALTER 在返回前已登记 mutation，不需要等待
+func (w *Watcher) lookupTimeout(ctx context.Context, database, table string) (time.Duration, error) {
+	if w.cluster != "" {
+		return w.lookup, nil
+	}
+
+	rows, err := w.client.Query(ctx, "SELECT engine FROM system.tables WHERE database = ? AND name = ?", database, table)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query table engine: %w", err)
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+	engine, _ := rows[0]["engine"].(string)
+	if strings.HasPrefix(engine, "Replicated") {
+		return w.lookup, nil
+	}
+	return 0, nil
+}
+
+// poll 查询一次 mutation 状态，多个 mutation（或多个节点）的状态合并为一个
+func (w *Watcher) poll(ctx context.Context, database, table string, since time.Time) (Status, error) {
+	source := "system.mutations"
+	if w.cluster != "" {
+		source = fmt.Sprintf("clusterAllReplicas(%s, system.mutations)", utils.EscapeString(w.cluster))
+	}
+
+	query := fmt.Sprintf(`
+		SELECT
+			mutation_id,
+			toInt64(parts_to_do) AS parts_to_do,
+			toUInt8(is_done) AS is_done,
+			latest_fail_reason
+		FROM %s
+		WHERE database = ?
+		  AND table = ?
+		  AND create_time >= toDateTime(?)
+		  AND command LIKE '%%TTL%%'
+		ORDER BY create_time, mutation_id
+	`, source)
+
+	// 预留几秒容忍客户端与服务端的时钟偏差
+	sinceSec := since.Add(-5 * time.Second).Unix()
+
+	rows, err := w.client.Query(ctx, query, database, table, sinceSec)
+	if err != nil {
+		return Status{}, fmt.Errorf("failed to query system.mutations: %w", err)
+	}
+
+	status := Status{Done: true}
+	seen := make(map[string]bool)
+	for _, row := range rows {
+		id, _ := row["mutation_id"].(string)
+		parts, _ := row["parts_to_do"].(int64)
+		done, _ := row["is_done"].(uint8)
+		reason, _ := row["latest_fail_reason"].(string)
+
+		if !seen[id] {
+			seen[id] = true
+			status.IDs = append(status.IDs, id)
+		}
+		status.PartsToDo += parts
+		if done == 0 {
+			status.Done = false
+		}
+		if reason != "" {
+			status.FailReason = reason
+		}
+	}
+
+	if len(status.IDs) == 0 {
+		status.Done = false
+	}
+
+	return status, nil
+}
@@ -0,0 +1,22 @@
+package mutation
+
+import "testing"
+
+func TestStatusState(t *testing.T) {
+	tests := []struct {
+		status Status
+		want   string
+	}{
+		{Status{}, "none"},
+		{Status{IDs: []string{"0000000001"}, PartsToDo: 3}, "running"},
+		{Status{IDs: []string{"0000000001"}, Done: true}, "done"},
+		{Status{IDs: []string{"0000000001"}, FailReason: "Code: 241. Memory limit exceeded"}, "failed"},
+		{Status{IDs: []string{"0000000001"}, Done: true, FailReason: "retried"}, "failed"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.status.State(); got != tt.want {
+			t.Errorf("State() of %+v = %q, want %q", tt.status, got, tt.want)
+		}
+	}
+}
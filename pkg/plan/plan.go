@@ -0,0 +1,145 @@
+// 使用方法：读写 plan 子命令生成的执行计划文件（JSON）
+// 计划记录每个表的检测结果、新旧 TTL、SQL 和表结构校验和，由 apply 子命令原样执行
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"clickhouse-ttl-tool/pkg/executor"
+	"clickhouse-ttl-tool/pkg/state"
+)
+
+// Version 计划文件格式版本
+const Version = 1
+
+// Plan 执行计划
+type Plan struct {
+	Version   int       `json:"version"`           // 文件格式版本
+	CreatedAt time.Time `json:"created_at"`        // 生成时间
+	Cluster   string    `json:"cluster,omitempty"` // ON CLUSTER 集群名
+	Databases []string  `json:"databases"`         // 扫描的数据库
+	Entries   []Entry   `json:"entries"`           // 每个表的计划
+}
+
+// Entry 单个表的计划
+type Entry struct {
+	Database    string `json:"database"`
+	Table       string `json:"table"`
+	Distributed string `json:"distributed,omitempty"` // 来源分布式表
+	Rule        string `json:"rule,omitempty"`        // 匹配的策略规则
+	TimeColumn  string `json:"time_column,omitempty"`
+	TimeType    string `json:"time_type,omitempty"`
+	TimeReason  string `json:"time_reason,omitempty"` // 选择该时间字段的依据
+	Retention   int    `json:"retention_days,omitempty"`
+	OldTTL      string `json:"old_ttl,omitempty"`
+	NewTTL      string `json:"new_ttl,omitempty"`
+	// 需要修改的列级 TTL
+	Columns    []state.ColumnTTL `json:"columns,omitempty"`
+	Change     string            `json:"change,omitempty"` // new / changed / unchanged
+	SQL        string            `json:"sql,omitempty"`
+	Checksum   string            `json:"checksum"` // 生成计划时的表结构校验和
+	Skipped    bool              `json:"skipped,omitempty"`
+	SkipReason string            `json:"skip_reason,omitempty"`
+	Warnings   []string          `json:"warnings,omitempty"`
+}
+
+// FromResults 根据 dry-run 的执行结果构建计划
+func FromResults(results []executor.ExecutionResult, cluster string, databases []string) *Plan {
+	p := &Plan{
+		Version:   Version,
+		CreatedAt: time.Now(),
+		Cluster:   cluster,
+		Databases: databases,
+		Entries:   make([]Entry, 0, len(results)),
+	}
+
+	for _, r := range results {
+		p.Entries = append(p.Entries, Entry{
+			Database:    r.Database,
+			Table:       r.Table,
+			Distributed: r.Distributed,
+			Rule:        r.Rule,
+			TimeColumn:  r.TimeColumn,
+			TimeType:    r.TimeType,
+			TimeReason:  r.TimeReason,
+			Retention:   r.Retention,
+			OldTTL:      r.OldTTL,
+			NewTTL:      r.NewTTL,
+			Columns:     r.Columns,
+			Change:      string(r.Change),
+			SQL:         r.SQL,
+			Checksum:    r.Checksum,
+			Skipped:     r.Skipped,
+			SkipReason:  r.SkipReason,
+			Warnings:    r.Warnings,
+		})
+	}
+
+	return p
+}
+
+// Actionable 返回需要执行的条目（未跳过的表）
+func (p *Plan) Actionable() []Entry {
+	var entries []Entry
+	for _, e := range p.Entries {
+		if !e.Skipped {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// Save 将计划写入文件
+func (p *Plan) Save(path string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode plan: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write plan file: %w", err)
+	}
+	return nil
+}
+
+// Load 从文件加载计划
+func Load(path string) (*Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan file: %w", err)
+	}
+
+	var p Plan
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("failed to parse plan file: %w", err)
+	}
+
+	if p.Version != Version {
+		return nil, fmt.Errorf("unsupported plan version %d, expected %d", p.Version, Version)
+	}
+
+	return &p, nil
+}
+
+// Result 将计划条目转换为执行结果，用于 apply 执行和报告
+func (e Entry) Result() executor.ExecutionResult {
+	return executor.ExecutionResult{
+		Database:    e.Database,
+		Table:       e.Table,
+		Distributed: e.Distributed,
+		Rule:        e.Rule,
+		TimeColumn:  e.TimeColumn,
+		TimeType:    e.TimeType,
+		TimeReason:  e.TimeReason,
+		Retention:   e.Retention,
+		OldTTL:      e.OldTTL,
+		NewTTL:      e.NewTTL,
+		Columns:     e.Columns,
+		Change:      executor.Change(e.Change),
+		SQL:         e.SQL,
+		Checksum:    e.Checksum,
+		Warnings:    e.Warnings,
+	}
+}
@@ -0,0 +1,48 @@
+package plan
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"clickhouse-ttl-tool/pkg/executor"
+)
+
+func TestSaveLoad(t *testing.T) {
+	results := []executor.ExecutionResult{
+		{Database: "logs", Table: "events", NewTTL: "`ts` + INTERVAL 30 DAY", Change: executor.ChangeNew, Checksum: "aaa"},
+		{Database: "logs", Table: "users", Skipped: true, SkipReason: "未找到时间列", Checksum: "bbb"},
+	}
+	p := FromResults(results, "main", []string{"logs"})
+
+	path := filepath.Join(t.TempDir(), "plan.json")
+	if err := p.Save(path); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !reflect.DeepEqual(loaded.Entries, p.Entries) {
+		t.Errorf("Load().Entries = %+v, want %+v", loaded.Entries, p.Entries)
+	}
+
+	entries := loaded.Actionable()
+	if len(entries) != 1 || entries[0].Table != "events" || entries[0].Checksum != "aaa" {
+		t.Fatalf("Actionable() = %+v, want only logs.events", entries)
+	}
+	if r := entries[0].Result(); r.Change != executor.ChangeNew || r.Checksum != "aaa" {
+		t.Errorf("Result() = %+v", r)
+	}
+}
+
+func TestLoadVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plan.json")
+	if err := os.WriteFile(path, []byte(`{"version": 99, "entries": []}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Fatal("Load() error = nil, want unsupported version error")
+	}
+}
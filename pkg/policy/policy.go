@@ -0,0 +1,530 @@
+// 使用方法：加载和匹配按表定义的 TTL 保留策略
+// 策略文件为 YAML 格式，规则按顺序匹配，首个命中的规则生效
+package policy
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"clickhouse-ttl-tool/pkg/matcher"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// Action TTL 动作
+type Action string
+
+const (
+	// ActionDelete 超过保留期的数据被删除
+	ActionDelete Action = "delete"
+	// ActionSkip 不为匹配的表设置 TTL
+	ActionSkip Action = "skip"
+)
+
+// Policy 保留策略
+type Policy struct {
+	Default *Rule  `yaml:"default"` // 默认规则（未匹配任何规则时使用）
+	Rules   []Rule `yaml:"rules"`   // 按顺序匹配的规则列表
+	// 时间字段候选（列名、glob 或 re: 前缀的正则），按优先级排列，为空时使用内置候选
+	TimeCandidates []string `yaml:"time_candidates"`
+
+	candidates []matcher.Pattern
+}
+
+// Rule 单条策略规则
+// Name/Glob/Regex 三者最多指定一个，且与 Database 至少指定其一（默认规则除外）
+type Rule struct {
+	Database      string `yaml:"database"`       // 数据库名或模式（glob 或 re: 前缀的正则），为空匹配所有库
+	Name          string `yaml:"name"`           // 精确表名
+	Glob          string `yaml:"glob"`           // glob 通配符
+	Regex         string `yaml:"regex"`          // 正则表达式
+	RetentionDays int    `yaml:"retention_days"` // 数据保留天数
+	TimeColumn    string `yaml:"time_column"`    // 指定时间字段（为空则自动检测）
+	Action        Action `yaml:"action"`         // TTL 动作，默认 delete
+	// 本规则的时间字段候选，覆盖全局候选，不能与 TimeColumn 同时指定
+	TimeCandidates []string `yaml:"time_candidates"`
+	// 文本时间字段的格式（parseDateTime 的 MySQL 语法，如 %d/%m/%Y %H:%i:%s），为空时按常见格式自动解析
+	TimeFormat string `yaml:"time_format"`
+	// 时间字段为 NULL 或文本无法解析的行立即过期；默认不过期（删除不可逆，需要显式开启）
+	ExpireInvalidTime bool `yaml:"expire_invalid_time"`
+	// 多条 TTL 子句（如按条件分别设置保留天数）
+	// 同时指定 RetentionDays 时，RetentionDays 作为最终的无条件删除，TTL 中不能再包含无条件删除
+	TTL []TTLRule `yaml:"ttl"`
+	// 列级 TTL（如超期后清空个人信息列），按列名模式匹配
+	ColumnTTL []ColumnTTL `yaml:"column_ttl"`
+
+	pattern    *matcher.Pattern
+	dbPattern  *matcher.Pattern
+	candidates []matcher.Pattern
+	isDefault  bool
+}
+
+// TTLRule 单条 TTL 子句，多条子句以逗号分隔组成表级 TTL
+// 指定 ToDisk/ToVolume 时为移动，指定 GroupBy 时为聚合，指定 Recompress 时为重新压缩，否则为删除
+type TTLRule struct {
+	RetentionDays int    `yaml:"retention_days"` // 数据保留天数（移动、聚合和重新压缩时为执行动作前的天数）
+	Where         string `yaml:"where"`          // WHERE 条件，仅删除和聚合支持，为空时作用于所有过期行
+	ToDisk        string `yaml:"to_disk"`        // 移动到的磁盘（TO DISK）
+	ToVolume      string `yaml:"to_volume"`      // 移动到的卷（TO VOLUME）
+	Recompress    string `yaml:"recompress"`     // 重新压缩使用的编解码器，如 ZSTD(17)
+	// 聚合键（GROUP BY），必须是表排序键的前缀
+	GroupBy []string `yaml:"group_by"`
+	// 聚合时非键列的取值（SET 列 = 聚合函数），未指定的列取任意值
+	Set map[string]string `yaml:"set"`
+
+	columns []string // WHERE、GROUP BY 和 SET 引用的列名，执行前校验表中是否存在
+}
+
+// IsMove 判断是否为移动子句（TO DISK / TO VOLUME）
+func (t TTLRule) IsMove() bool {
+	return t.ToDisk != "" || t.ToVolume != ""
+}
+
+// IsGroupBy 判断是否为聚合子句（GROUP BY）
+func (t TTLRule) IsGroupBy() bool {
+	return len(t.GroupBy) > 0
+}
+
+// IsRecompress 判断是否为重新压缩子句（RECOMPRESS CODEC）
+func (t TTLRule) IsRecompress() bool {
+	return t.Recompress != ""
+}
+
+// IsDelete 判断是否为删除子句
+func (t TTLRule) IsDelete() bool {
+	return !t.IsMove() && !t.IsGroupBy() && !t.IsRecompress()
+}
+
+// validate 校验子句的动作组合，并解析引用的列
+func (t *TTLRule) validate() error {
+	t.Where = strings.TrimSpace(t.Where)
+
+	if t.ToDisk != "" && t.ToVolume != "" {
+		return errors.New("only one of to_disk or to_volume can be set")
+	}
+	if t.IsRecompress() {
+		if t.IsMove() || t.IsGroupBy() {
+			return errors.New("recompress cannot be combined with to_disk, to_volume or group_by")
+		}
+		if t.Where != "" {
+			return errors.New("where is not supported for recompress")
+		}
+		t.Recompress = strings.TrimSpace(t.Recompress)
+		if _, err := predicateColumns(t.Recompress); err != nil {
+			return fmt.Errorf("invalid recompress codec %q: %w", t.Recompress, err)
+		}
+	}
+	if t.IsMove() {
+		if t.IsGroupBy() {
+			return errors.New("group_by cannot be combined with to_disk or to_volume")
+		}
+		// ClickHouse 仅支持对删除和聚合使用 WHERE
+		if t.Where != "" {
+			return errors.New("where is not supported for to_disk or to_volume")
+		}
+	}
+	if len(t.Set) > 0 && !t.IsGroupBy() {
+		return errors.New("set requires group_by")
+	}
+
+	exprs := []string{t.Where}
+	for i, k := range t.GroupBy {
+		t.GroupBy[i] = strings.TrimSpace(k)
+		if t.GroupBy[i] == "" {
+			return errors.New("group_by must not contain empty expressions")
+		}
+		exprs = append(exprs, t.GroupBy[i])
+	}
+	for col, expr := range t.Set {
+		if strings.TrimSpace(expr) == "" {
+			return fmt.Errorf("set %s: empty expression", col)
+		}
+		exprs = append(exprs, expr)
+	}
+
+	// 收集 WHERE、GROUP BY 和 SET 中引用的列，供执行前校验
+	seen := make(map[string]bool)
+	add := func(cols ...string) {
+		for _, c := range cols {
+			if !seen[c] {
+				seen[c] = true
+				t.columns = append(t.columns, c)
+			}
+		}
+	}
+	for _, expr := range exprs {
+		if expr == "" {
+			continue
+		}
+		cols, err := predicateColumns(expr)
+		if err != nil {
+			return fmt.Errorf("invalid expression %q: %w", expr, err)
+		}
+		add(cols...)
+	}
+	for _, col := range t.SetColumns() {
+		add(col)
+	}
+
+	return nil
+}
+
+// SetColumns 返回 SET 中赋值的列名（按名称排序，保证生成的语句稳定）
+func (t TTLRule) SetColumns() []string {
+	cols := make([]string, 0, len(t.Set))
+	for c := range t.Set {
+		cols = append(cols, c)
+	}
+	sort.Strings(cols)
+	return cols
+}
+
+// ColumnTTL 列级 TTL 规则，匹配的列在超过保留天数后被重置为默认值
+type ColumnTTL struct {
+	Columns       []string `yaml:"columns"`        // 列名或模式（glob 或 re: 前缀的正则）
+	RetentionDays int      `yaml:"retention_days"` // 列数据保留天数
+
+	patterns []matcher.Pattern
+}
+
+// Match 判断列名是否匹配
+func (c ColumnTTL) Match(column string) bool {
+	return matcher.MatchAny(c.patterns, column)
+}
+
+// compile 校验列级 TTL 规则并编译列名模式
+func (c *ColumnTTL) compile() error {
+	if len(c.Columns) == 0 {
+		return errors.New("columns must not be empty")
+	}
+	if c.RetentionDays <= 0 {
+		return fmt.Errorf("invalid retention days: %d, must be greater than 0", c.RetentionDays)
+	}
+
+	patterns, err := matcher.ParseAll(c.Columns)
+	if err != nil {
+		return err
+	}
+	c.patterns = patterns
+	return nil
+}
+
+// Load 从 YAML 文件加载策略
+// 未知字段（如拼错的 time_colum）视为错误，避免配置被静默忽略后按默认行为删除数据
+func Load(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy file: %w", err)
+	}
+
+	var p Policy
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&p); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse policy file: %w", err)
+	}
+
+	if err := p.compile(); err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+// FromRetention 根据单一保留天数构建策略（未提供策略文件时的默认行为）
+func FromRetention(days int) *Policy {
+	p := &Policy{
+		Default: &Rule{RetentionDays: days},
+	}
+	// 单值策略只包含默认规则，不会编译失败
+	_ = p.compile()
+	return p
+}
+
+// SetDefaultRetention 策略文件未定义默认规则时，使用指定天数作为默认规则
+func (p *Policy) SetDefaultRetention(days int) {
+	if p.Default != nil || days <= 0 {
+		return
+	}
+	p.Default = &Rule{RetentionDays: days, Action: ActionDelete, isDefault: true}
+}
+
+// compile 校验规则并预编译匹配模式
+func (p *Policy) compile() error {
+	candidates, err := matcher.ParseAll(p.TimeCandidates)
+	if err != nil {
+		return fmt.Errorf("time_candidates: %w", err)
+	}
+	p.candidates = candidates
+
+	for i := range p.Rules {
+		r := &p.Rules[i]
+		if err := r.compile(); err != nil {
+			return fmt.Errorf("rule #%d: %w", i+1, err)
+		}
+	}
+
+	if p.Default != nil {
+		if p.Default.Database != "" || p.Default.Name != "" || p.Default.Glob != "" || p.Default.Regex != "" {
+			return errors.New("default rule must not specify database, name, glob or regex")
+		}
+		p.Default.isDefault = true
+		if err := p.Default.validate(); err != nil {
+			return fmt.Errorf("default rule: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// compile 校验单条规则并编译匹配模式
+func (r *Rule) compile() error {
+	count := 0
+	var (
+		p   matcher.Pattern
+		err error
+	)
+	if r.Name != "" {
+		count++
+		p = matcher.Exact(r.Name)
+	}
+	if r.Glob != "" {
+		count++
+		p, err = matcher.Glob(r.Glob)
+	}
+	if r.Regex != "" {
+		count++
+		p, err = matcher.Regex(r.Regex)
+	}
+	if err != nil {
+		return err
+	}
+	if count > 1 {
+		return errors.New("only one of name, glob or regex can be set")
+	}
+	if count == 1 {
+		r.pattern = &p
+	}
+
+	if r.Database != "" {
+		dbp, err := matcher.Parse(r.Database)
+		if err != nil {
+			return err
+		}
+		r.dbPattern = &dbp
+	}
+
+	if r.pattern == nil && r.dbPattern == nil {
+		return errors.New("one of database, name, glob or regex must be set")
+	}
+
+	return r.validate()
+}
+
+// validate 校验规则的动作、保留天数和时间字段候选
+func (r *Rule) validate() error {
+	if r.Action == "" {
+		r.Action = ActionDelete
+	}
+
+	if r.TimeColumn != "" && len(r.TimeCandidates) > 0 {
+		return errors.New("only one of time_column or time_candidates can be set")
+	}
+	candidates, err := matcher.ParseAll(r.TimeCandidates)
+	if err != nil {
+		return fmt.Errorf("time_candidates: %w", err)
+	}
+	r.candidates = candidates
+
+	switch r.Action {
+	case ActionDelete:
+		for i := range r.ColumnTTL {
+			if err := r.ColumnTTL[i].compile(); err != nil {
+				return fmt.Errorf("column_ttl #%d: %w", i+1, err)
+			}
+		}
+		if len(r.TTL) > 0 {
+			return r.validateTTL()
+		}
+		// 只设置列级 TTL 时可以不指定表级保留天数
+		if r.RetentionDays == 0 && len(r.ColumnTTL) > 0 {
+			return nil
+		}
+		if r.RetentionDays <= 0 {
+			return fmt.Errorf("invalid retention days: %d, must be greater than 0", r.RetentionDays)
+		}
+	case ActionSkip:
+	default:
+		return fmt.Errorf("unknown action %q, must be one of: delete, skip", r.Action)
+	}
+
+	return nil
+}
+
+// validateTTL 校验多条 TTL 子句并解析子句引用的列
+func (r *Rule) validateTTL() error {
+	if r.RetentionDays < 0 {
+		return fmt.Errorf("invalid retention days: %d, must be greater than 0", r.RetentionDays)
+	}
+
+	// retention_days 本身即一条无条件删除
+	unconditional := 0
+	if r.RetentionDays > 0 {
+		unconditional++
+	}
+	for i := range r.TTL {
+		t := &r.TTL[i]
+		if t.RetentionDays <= 0 {
+			return fmt.Errorf("ttl #%d: invalid retention days: %d, must be greater than 0", i+1, t.RetentionDays)
+		}
+
+		if err := t.validate(); err != nil {
+			return fmt.Errorf("ttl #%d: %w", i+1, err)
+		}
+		if t.IsDelete() && t.Where == "" {
+			unconditional++
+		}
+	}
+
+	// ClickHouse 不允许多条不带 WHERE 的 DELETE TTL
+	if unconditional > 1 {
+		return errors.New("at most one delete without where is allowed (including retention_days)")
+	}
+
+	return nil
+}
+
+// TTLRules 返回规则对应的 TTL 子句
+// RetentionDays 构成一条无条件删除，追加在 ttl 中的其他阶段之后
+func (r *Rule) TTLRules() []TTLRule {
+	if r.RetentionDays <= 0 {
+		return r.TTL
+	}
+	rules := make([]TTLRule, 0, len(r.TTL)+1)
+	rules = append(rules, r.TTL...)
+	return append(rules, TTLRule{RetentionDays: r.RetentionDays})
+}
+
+// CandidatePatterns 返回规则的时间字段候选，未指定时返回 nil
+func (r *Rule) CandidatePatterns() []matcher.Pattern {
+	return r.candidates
+}
+
+// MissingColumns 返回 TTL 子句引用但表中不存在的列
+// columns 为表的列名到类型的映射
+func (r *Rule) MissingColumns(columns map[string]string) []string {
+	var missing []string
+	seen := make(map[string]bool)
+	for _, t := range r.TTLRules() {
+		for _, c := range t.columns {
+			if _, ok := columns[c]; !ok && !seen[c] {
+				seen[c] = true
+				missing = append(missing, c)
+			}
+		}
+	}
+	return missing
+}
+
+// ColumnRetention 返回列匹配的首条列级 TTL 规则的保留天数，未匹配时返回 0
+func (r *Rule) ColumnRetention(column string) int {
+	for _, c := range r.ColumnTTL {
+		if c.Match(column) {
+			return c.RetentionDays
+		}
+	}
+	return 0
+}
+
+// HasMoves 判断规则是否包含移动子句
+func (r *Rule) HasMoves() bool {
+	for _, t := range r.TTLRules() {
+		if t.IsMove() {
+			return true
+		}
+	}
+	return false
+}
+
+// ReferencesColumns 判断规则的 TTL 子句是否引用了时间字段以外的列（WHERE/GROUP BY/SET）
+func (r *Rule) ReferencesColumns() bool {
+	for _, t := range r.TTLRules() {
+		if len(t.columns) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// HasGroupBy 判断规则是否包含聚合子句
+func (r *Rule) HasGroupBy() bool {
+	for _, t := range r.TTLRules() {
+		if t.IsGroupBy() {
+			return true
+		}
+	}
+	return false
+}
+
+// HasMoves 判断策略中是否有规则包含移动子句
+func (p *Policy) HasMoves() bool {
+	if p.Default != nil && p.Default.HasMoves() {
+		return true
+	}
+	for i := range p.Rules {
+		if p.Rules[i].HasMoves() {
+			return true
+		}
+	}
+	return false
+}
+
+// CandidatePatterns 返回策略文件的全局时间字段候选，未指定时返回 nil
+func (p *Policy) CandidatePatterns() []matcher.Pattern {
+	return p.candidates
+}
+
+// Match 返回表匹配的首条规则，未匹配任何规则时返回默认规则
+// 没有默认规则且未匹配时返回 nil
+func (p *Policy) Match(database, table string) *Rule {
+	for i := range p.Rules {
+		if p.Rules[i].matches(database, table) {
+			return &p.Rules[i]
+		}
+	}
+	return p.Default
+}
+
+// matches 判断规则是否匹配指定的表
+func (r *Rule) matches(database, table string) bool {
+	if r.dbPattern != nil && !r.dbPattern.Match(database) {
+		return false
+	}
+	return r.pattern == nil || r.pattern.Match(table)
+}
+
+// IsDefault 判断是否为默认规则
+func (r *Rule) IsDefault() bool {
+	return r.isDefault
+}
+
+// String 返回规则的可读描述，用于报告输出
+func (r *Rule) String() string {
+	if r.isDefault {
+		return "default"
+	}
+
+	var parts []string
+	if r.dbPattern != nil {
+		parts = append(parts, "database="+r.dbPattern.String())
+	}
+	if r.pattern != nil {
+		parts = append(parts, r.pattern.String())
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,178 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writePolicy 将策略内容写入临时文件并返回路径
+func writePolicy(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	path := writePolicy(t, `
+default:
+  retention_days: 30
+time_candidates: [event_time, "*_at"]
+rules:
+  - name: audit_log
+    action: skip
+  - database: "logs_*"
+    glob: "access_*"
+    retention_days: 7
+    time_format: "%d/%m/%Y %H:%i:%s"
+    expire_invalid_time: true
+  - regex: "^metrics_\\d+$"
+    time_candidates: [ts]
+    retention_days: 90
+    ttl:
+      - retention_days: 3
+        where: "level = 'debug'"
+    column_ttl:
+      - columns: [email, "re:^phone"]
+        retention_days: 14
+`)
+
+	p, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if p.Default == nil || !p.Default.IsDefault() || p.Default.RetentionDays != 30 {
+		t.Fatalf("Default = %+v, want default rule with 30 days", p.Default)
+	}
+	if len(p.Rules) != 3 {
+		t.Fatalf("len(Rules) = %d, want 3", len(p.Rules))
+	}
+	if got := len(p.CandidatePatterns()); got != 2 {
+		t.Errorf("len(CandidatePatterns()) = %d, want 2", got)
+	}
+
+	r := p.Rules[1]
+	if r.Action != ActionDelete || r.TimeFormat != "%d/%m/%Y %H:%i:%s" || !r.ExpireInvalidTime {
+		t.Errorf("rule #2 = %+v", r)
+	}
+	r = p.Rules[2]
+	if len(r.TTL) != 1 || r.TTL[0].Where != "level = 'debug'" {
+		t.Errorf("rule #3 ttl = %+v", r.TTL)
+	}
+	if r.ColumnRetention("email") != 14 || r.ColumnRetention("phone_number") != 14 || r.ColumnRetention("name") != 0 {
+		t.Errorf("rule #3 column retention mismatch")
+	}
+}
+
+func TestLoadEmpty(t *testing.T) {
+	p, err := Load(writePolicy(t, ""))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if p.Default != nil || len(p.Rules) != 0 {
+		t.Errorf("Load() = %+v, want empty policy", p)
+	}
+}
+
+func TestLoadUnknownField(t *testing.T) {
+	_, err := Load(writePolicy(t, `
+rules:
+  - name: events
+    retention_days: 7
+    time_colum: created_at
+`))
+	if err == nil || !strings.Contains(err.Error(), "time_colum") {
+		t.Fatalf("Load() error = %v, want unknown field error", err)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{
+			name:    "name and glob",
+			content: "rules:\n  - name: a\n    glob: \"b*\"\n    retention_days: 1\n",
+			wantErr: "only one of name, glob or regex",
+		},
+		{
+			name:    "no pattern",
+			content: "rules:\n  - retention_days: 1\n",
+			wantErr: "one of database, name, glob or regex must be set",
+		},
+		{
+			name:    "time column and candidates",
+			content: "rules:\n  - name: a\n    retention_days: 1\n    time_column: ts\n    time_candidates: [dt]\n",
+			wantErr: "only one of time_column or time_candidates",
+		},
+		{
+			name:    "zero retention",
+			content: "rules:\n  - name: a\n",
+			wantErr: "invalid retention days",
+		},
+		{
+			name:    "unknown action",
+			content: "rules:\n  - name: a\n    retention_days: 1\n    action: archive\n",
+			wantErr: "unknown action",
+		},
+		{
+			name:    "default with pattern",
+			content: "default:\n  name: a\n  retention_days: 1\n",
+			wantErr: "default rule must not specify",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(writePolicy(t, tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Load() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	p, err := Load(writePolicy(t, `
+default:
+  retention_days: 30
+rules:
+  - name: audit_log
+    action: skip
+  - database: "logs_*"
+    retention_days: 7
+  - glob: "tmp_*"
+    retention_days: 1
+`))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	tests := []struct {
+		database, table string
+		want            string
+	}{
+		{"default", "audit_log", "audit_log"},
+		{"logs_2024", "audit_log", "audit_log"},
+		{"logs_2024", "tmp_x", "database=glob:logs_*"},
+		{"default", "tmp_x", "glob:tmp_*"},
+		{"default", "events", "default"},
+	}
+	for _, tt := range tests {
+		r := p.Match(tt.database, tt.table)
+		if r == nil || r.String() != tt.want {
+			t.Errorf("Match(%q, %q) = %v, want %s", tt.database, tt.table, r, tt.want)
+		}
+	}
+
+	p.Default = nil
+	if r := p.Match("default", "events"); r != nil {
+		t.Errorf("Match() without default = %v, want nil", r)
+	}
+}
@@ -0,0 +1,76 @@
+// 使用方法：解析 TTL 子句（WHERE 条件、GROUP BY 键、SET 表达式）中引用的列名
+// 基于 lexer 的词法单元：跳过字符串字面量、数字、关键字和函数名，其余标识符视为列名
+package policy
+
+import (
+	"errors"
+	"strings"
+
+	"clickhouse-ttl-tool/pkg/lexer"
+)
+
+// predicateKeywords 条件表达式中可能出现的关键字，不视为列名
+var predicateKeywords = map[string]bool{
+	"and": true, "or": true, "not": true, "in": true, "like": true, "ilike": true,
+	"is": true, "null": true, "between": true, "true": true, "false": true,
+	"case": true, "when": true, "then": true, "else": true, "end": true,
+	"global": true, "any": true, "all": true, "as": true, "interval": true,
+	"second": true, "minute": true, "hour": true, "day": true,
+	"week": true, "month": true, "quarter": true, "year": true,
+}
+
+// predicateColumns 返回条件表达式引用的列名（按首次出现顺序去重）
+// 函数名（后跟左括号）和 lambda 参数（后跟 ->）不计入
+func predicateColumns(expr string) ([]string, error) {
+	tokens, err := lexer.Tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		idents []string
+		lambda = make(map[string]bool)
+		depth  int
+	)
+
+	for i, t := range tokens {
+		switch {
+		case t.IsIdent():
+			switch {
+			case lexer.IsCall(tokens, i):
+			case i+1 < len(tokens) && tokens[i+1].Is("->"):
+				lambda[t.Value] = true
+			case t.Kind == lexer.Ident && predicateKeywords[strings.ToLower(t.Value)]:
+			default:
+				idents = append(idents, t.Value)
+			}
+
+		case t.Is("("):
+			depth++
+
+		case t.Is(")"):
+			depth--
+			if depth < 0 {
+				return nil, errors.New("unbalanced parentheses")
+			}
+
+		case t.Is(";"):
+			return nil, errors.New("multiple statements are not allowed")
+		}
+	}
+
+	if depth != 0 {
+		return nil, errors.New("unbalanced parentheses")
+	}
+
+	var columns []string
+	seen := make(map[string]bool)
+	for _, ident := range idents {
+		if lambda[ident] || seen[ident] {
+			continue
+		}
+		seen[ident] = true
+		columns = append(columns, ident)
+	}
+	return columns, nil
+}
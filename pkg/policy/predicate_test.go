@@ -0,0 +1,45 @@
+package policy
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPredicateColumns(t *testing.T) {
+	tests := []struct {
+		expr    string
+		want    []string
+		wantErr bool
+	}{
+		{expr: "level = 'debug'", want: []string{"level"}},
+		{expr: "level IN ('debug', 'trace') AND NOT is_pinned", want: []string{"level", "is_pinned"}},
+		{expr: "toDate(ts) < today() - INTERVAL 1 DAY", want: []string{"ts"}},
+		{expr: "`user id` = 1 OR \"tenant\" = 2 OR level = 'a''b'", want: []string{"user id", "tenant", "level"}},
+		{expr: "arrayExists(x -> x > 10, scores) AND attrs.key = 'k'", want: []string{"scores", "attrs.key"}},
+		{expr: "status = 1 AND status = 2 AND size > 1.5e3", want: []string{"status", "size"}},
+		{expr: "sum(bytes)", want: []string{"bytes"}},
+		{expr: "now() > toDateTime(0)", want: nil},
+		{expr: "(level = 'debug'", wantErr: true},
+		{expr: "level = 'debug')", wantErr: true},
+		{expr: "level = 'debug", wantErr: true},
+		{expr: "1; DROP TABLE t", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := predicateColumns(tt.expr)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("predicateColumns() = %q, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("predicateColumns() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("predicateColumns() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,283 @@
+// 使用方法：将执行结果和统计摘要序列化为 JSON/CSV/文本
+// 供仪表盘和工单自动化等程序消费，避免解析终端输出
+package reporter
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"clickhouse-ttl-tool/pkg/executor"
+	"clickhouse-ttl-tool/pkg/state"
+)
+
+// Format 报告格式
+type Format string
+
+const (
+	// FormatText 纯文本
+	FormatText Format = "text"
+	// FormatJSON JSON
+	FormatJSON Format = "json"
+	// FormatCSV CSV
+	FormatCSV Format = "csv"
+)
+
+// ParseFormat 解析报告格式
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case FormatText, FormatJSON, FormatCSV:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unknown output format %q, must be one of: text, json, csv", s)
+	}
+}
+
+// Record 单个表的序列化结果
+type Record struct {
+	Database    string `json:"database"`
+	Table       string `json:"table"`
+	Distributed string `json:"distributed,omitempty"`
+	Rule        string `json:"rule,omitempty"`
+	TimeColumn  string `json:"time_column,omitempty"`
+	TimeType    string `json:"time_type,omitempty"`
+	TimeReason  string `json:"time_reason,omitempty"`
+	Retention   int    `json:"retention_days,omitempty"`
+	Status      string `json:"status"` // success / failed / skipped
+	Change      string `json:"change,omitempty"`
+	OldTTL      string `json:"old_ttl,omitempty"`
+	NewTTL      string `json:"new_ttl,omitempty"`
+	// 修改的列级 TTL
+	Columns     []state.ColumnTTL `json:"columns,omitempty"`
+	SQL         string            `json:"sql,omitempty"`
+	Error       string            `json:"error,omitempty"`
+	SkipReason  string            `json:"skip_reason,omitempty"`
+	FailedHosts []string          `json:"failed_hosts,omitempty"`
+	// 逐个物化 TTL 的分区 ID
+	Partitions []string `json:"partitions,omitempty"`
+	// 执行前因服务端负载过高而等待的秒数
+	WaitedSeconds float64 `json:"waited_seconds,omitempty"`
+	// --wait 模式下 mutation 的最终状态（none / done / failed / running）和 ID
+	Mutation    string   `json:"mutation,omitempty"`
+	MutationIDs []string `json:"mutation_ids,omitempty"`
+	// 需要使用者注意的问题
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// Report 完整的序列化报告
+type Report struct {
+	DryRun  bool          `json:"dry_run"`
+	Summary SummaryRecord `json:"summary"`
+	Results []Record      `json:"results"`
+}
+
+// SummaryRecord 统计摘要的序列化形式
+type SummaryRecord struct {
+	Total           int               `json:"total"`
+	Success         int               `json:"success"`
+	Failed          int               `json:"failed"`
+	Skipped         int               `json:"skipped"`
+	New             int               `json:"new"`
+	Changed         int               `json:"changed"`
+	Unchanged       int               `json:"unchanged"`
+	Removed         int               `json:"removed"`
+	DurationSeconds float64           `json:"duration_seconds"`
+	WaitedSeconds   float64           `json:"waited_seconds"`
+	ByDatabase      []DatabaseSummary `json:"by_database,omitempty"`
+}
+
+// csvHeader CSV 列名，顺序与 csvRow 一致
+var csvHeader = []string{
+	"database", "table", "distributed", "rule", "time_column", "time_type", "retention_days",
+	"status", "change", "old_ttl", "new_ttl", "columns", "sql", "error", "skip_reason", "failed_hosts",
+	"partitions", "waited_seconds", "mutation", "mutation_ids", "warnings", "time_reason",
+}
+
+// NewRecord 将执行结果转换为序列化记录
+func NewRecord(result executor.ExecutionResult) Record {
+	rec := Record{
+		Database:      result.Database,
+		Table:         result.Table,
+		Distributed:   result.Distributed,
+		Rule:          result.Rule,
+		TimeColumn:    result.TimeColumn,
+		TimeType:      result.TimeType,
+		TimeReason:    result.TimeReason,
+		Retention:     result.Retention,
+		Status:        status(result),
+		Change:        string(result.Change),
+		OldTTL:        result.OldTTL,
+		NewTTL:        result.NewTTL,
+		Columns:       result.Columns,
+		SQL:           result.SQL,
+		SkipReason:    result.SkipReason,
+		FailedHosts:   result.FailedHosts,
+		Partitions:    result.Partitions,
+		WaitedSeconds: result.Waited.Seconds(),
+		Warnings:      result.Warnings,
+	}
+	if result.Error != nil {
+		rec.Error = result.Error.Error()
+	}
+	if result.Mutation != nil {
+		rec.Mutation = result.Mutation.State()
+		rec.MutationIDs = result.Mutation.IDs
+	}
+	return rec
+}
+
+// status 返回执行结果的状态
+func status(result executor.ExecutionResult) string {
+	switch {
+	case result.Skipped:
+		return "skipped"
+	case result.Success:
+		return "success"
+	default:
+		return "failed"
+	}
+}
+
+// BuildReport 构建完整的序列化报告
+func (r *Reporter) BuildReport(summary Summary) Report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	report := Report{
+		DryRun: r.dryRun,
+		Summary: SummaryRecord{
+			Total:           summary.Total,
+			Success:         summary.Success,
+			Failed:          summary.Failed,
+			Skipped:         summary.Skipped,
+			New:             summary.New,
+			Changed:         summary.Changed,
+			Unchanged:       summary.Unchanged,
+			Removed:         summary.Removed,
+			DurationSeconds: summary.Duration.Seconds(),
+			WaitedSeconds:   summary.Waited.Seconds(),
+			ByDatabase:      summary.ByDatabase,
+		},
+		Results: make([]Record, 0, len(r.results)),
+	}
+
+	for _, result := range r.results {
+		report.Results = append(report.Results, NewRecord(result))
+	}
+
+	return report
+}
+
+// Export 按指定格式将报告写入 path 指定的文件，输出到其他目标时使用 Write
+func (r *Reporter) Export(format Format, path string, summary Summary) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create report file: %w", err)
+	}
+	defer f.Close()
+	return r.Write(f, format, summary)
+}
+
+// Write 将报告按指定格式写入 w
+func (r *Reporter) Write(w io.Writer, format Format, summary Summary) error {
+	report := r.BuildReport(summary)
+
+	switch format {
+	case FormatJSON:
+		return writeJSON(w, report)
+	case FormatCSV:
+		return writeCSV(w, report)
+	default:
+		return writeText(w, report)
+	}
+}
+
+// writeJSON 输出 JSON 报告
+func writeJSON(w io.Writer, report Report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return fmt.Errorf("failed to write JSON report: %w", err)
+	}
+	return nil
+}
+
+// writeCSV 输出 CSV 报告
+// 统计摘要以 # 开头的注释行写在表头之前，csv.Reader 设置 Comment = '#' 即可跳过
+func writeCSV(w io.Writer, report Report) error {
+	s := report.Summary
+	if _, err := fmt.Fprintf(w,
+		"# dry_run=%t total=%d success=%d failed=%d skipped=%d new=%d changed=%d unchanged=%d removed=%d duration_seconds=%.2f waited_seconds=%.2f\n",
+		report.DryRun, s.Total, s.Success, s.Failed, s.Skipped, s.New, s.Changed, s.Unchanged, s.Removed, s.DurationSeconds, s.WaitedSeconds,
+	); err != nil {
+		return fmt.Errorf("failed to write CSV report: %w", err)
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return fmt.Errorf("failed to write CSV report: %w", err)
+	}
+	for _, rec := range report.Results {
+		if err := cw.Write(csvRow(rec)); err != nil {
+			return fmt.Errorf("failed to write CSV report: %w", err)
+		}
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV report: %w", err)
+	}
+	return nil
+}
+
+// csvRow 将记录转换为 CSV 行
+func csvRow(rec Record) []string {
+	retention := ""
+	if rec.Retention > 0 {
+		retention = strconv.Itoa(rec.Retention)
+	}
+	waited := ""
+	if rec.WaitedSeconds > 0 {
+		waited = strconv.FormatFloat(rec.WaitedSeconds, 'f', 2, 64)
+	}
+	columns := make([]string, 0, len(rec.Columns))
+	for _, c := range rec.Columns {
+		columns = append(columns, c.Column+": "+c.NewTTL)
+	}
+	return []string{
+		rec.Database, rec.Table, rec.Distributed, rec.Rule, rec.TimeColumn, rec.TimeType, retention,
+		rec.Status, rec.Change, rec.OldTTL, rec.NewTTL, strings.Join(columns, "; "),
+		rec.SQL, rec.Error, rec.SkipReason, strings.Join(rec.FailedHosts, "; "),
+		strings.Join(rec.Partitions, "; "), waited, rec.Mutation, strings.Join(rec.MutationIDs, "; "),
+		strings.Join(rec.Warnings, "; "), rec.TimeReason,
+	}
+}
+
+// writeText 输出不含装饰符号的纯文本报告
+func writeText(w io.Writer, report Report) error {
+	var b strings.Builder
+	for _, rec := range report.Results {
+		fmt.Fprintf(&b, "%s.%s\t%s", rec.Database, rec.Table, rec.Status)
+		switch {
+		case rec.Error != "":
+			fmt.Fprintf(&b, "\t%s", rec.Error)
+		case rec.SkipReason != "":
+			fmt.Fprintf(&b, "\t%s", rec.SkipReason)
+		case rec.SQL != "":
+			fmt.Fprintf(&b, "\t%s", rec.SQL)
+		}
+		b.WriteString("\n")
+	}
+
+	s := report.Summary
+	fmt.Fprintf(&b, "total=%d success=%d failed=%d skipped=%d new=%d changed=%d unchanged=%d removed=%d duration_seconds=%.2f waited_seconds=%.2f\n",
+		s.Total, s.Success, s.Failed, s.Skipped, s.New, s.Changed, s.Unchanged, s.Removed, s.DurationSeconds, s.WaitedSeconds)
+
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return fmt.Errorf("failed to write text report: %w", err)
+	}
+	return nil
+}
@@ -4,32 +4,55 @@ package reporter
 
 import (
 	"fmt"
+	"io"
 	"strings"
+	"sync"
 	"time"
 
 	"clickhouse-ttl-tool/pkg/executor"
+	"clickhouse-ttl-tool/pkg/mutation"
 )
 
 // Reporter 报告生成器
+// 所有方法都可被多个 goroutine 并发调用，结果按 AddResult 的调用顺序保存
 type Reporter struct {
+	out       io.Writer // 进度和总结的输出目标
 	results   []executor.ExecutionResult
 	startTime time.Time
 	verbose   bool
 	dryRun    bool
+	mu        sync.Mutex
 }
 
 // Summary 执行统计摘要
 type Summary struct {
-	Total    int           // 总表数
-	Success  int           // 成功数
-	Failed   int           // 失败数
-	Skipped  int           // 跳过数
-	Duration time.Duration // 执行耗时
+	Total      int               // 总表数
+	Success    int               // 成功数
+	Failed     int               // 失败数
+	Skipped    int               // 跳过数
+	New        int               // 原本没有 TTL 的表数
+	Changed    int               // TTL 被修改的表数
+	Unchanged  int               // TTL 未变化而跳过的表数
+	Removed    int               // TTL 被移除的表数
+	Duration   time.Duration     // 执行耗时
+	Waited     time.Duration     // 因服务端负载过高而等待的累计时长（并发时各表分别计算）
+	ByDatabase []DatabaseSummary // 按数据库分组的统计（按首次出现顺序）
+}
+
+// DatabaseSummary 单个数据库的执行统计
+type DatabaseSummary struct {
+	Database string `json:"database"` // 数据库名
+	Total    int    `json:"total"`    // 总表数
+	Success  int    `json:"success"`  // 成功数
+	Failed   int    `json:"failed"`   // 失败数
+	Skipped  int    `json:"skipped"`  // 跳过数
 }
 
 // NewReporter 创建新的报告器
-func NewReporter(verbose, dryRun bool) *Reporter {
+// out 为进度和总结的输出目标，结构化报告输出到标准输出时应为标准错误
+func NewReporter(out io.Writer, verbose, dryRun bool) *Reporter {
 	return &Reporter{
+		out:       out,
 		results:   make([]executor.ExecutionResult, 0),
 		startTime: time.Now(),
 		verbose:   verbose,
@@ -39,47 +62,141 @@ func NewReporter(verbose, dryRun bool) *Reporter {
 
 // AddResult 添加执行结果
 func (r *Reporter) AddResult(result executor.ExecutionResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	r.results = append(r.results, result)
 }
 
 // PrintProgress 打印单个表的执行进度
 func (r *Reporter) PrintProgress(index, total int, result executor.ExecutionResult) {
+	// 加锁避免多个表的输出交错
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	// 打印进度头
-	fmt.Printf("\n[%d/%d] %s.%s\n", index, total, result.Database, result.Table)
+	fmt.Fprintf(r.out, "\n[%d/%d] %s.%s\n", index, total, result.Database, result.Table)
+
+	// 分布式表到本地表的映射
+	if result.Distributed != "" {
+		fmt.Fprintf(r.out, "  → 分布式表 %s 的本地表\n", result.Distributed)
+	}
+
+	// 匹配的策略规则
+	if result.Rule != "" && r.verbose {
+		fmt.Fprintf(r.out, "  → 策略规则: %s\n", result.Rule)
+	}
 
 	// 跳过的表
 	if result.Skipped {
-		fmt.Printf("  ✗ 跳过: %s\n", result.SkipReason)
+		fmt.Fprintf(r.out, "  ✗ 跳过: %s\n", result.SkipReason)
 		return
 	}
 
-	// 显示找到的时间字段
-	timeTypeDesc := result.TimeType
-	if result.TimeType != "" {
-		// 提取简化的类型名
-		timeTypeDesc = result.TimeType
+	// 显示找到的时间字段（回滚等不涉及时间字段的操作不显示）
+	if result.TimeColumn != "" {
+		timeTypeDesc := result.TimeType
+		if result.TimeType != "" {
+			// 提取简化的类型名
+			timeTypeDesc = result.TimeType
+		}
+		fmt.Fprintf(r.out, "  ✓ 找到时间字段: %s (%s)\n", result.TimeColumn, timeTypeDesc)
+		if result.TimeReason != "" {
+			fmt.Fprintf(r.out, "    依据: %s\n", result.TimeReason)
+		}
+		for _, w := range result.Warnings {
+			fmt.Fprintf(r.out, "  ⚠ %s\n", w)
+		}
+		// 仅包含移动子句时没有保留天数
+		if result.Retention > 0 {
+			fmt.Fprintf(r.out, "  ✓ 保留天数: %d 天\n", result.Retention)
+		}
+	}
+
+	// 与现有 TTL 的比较
+	switch result.Change {
+	case executor.ChangeNew:
+		fmt.Fprintf(r.out, "  → TTL 变更: 新增\n")
+	case executor.ChangeChanged:
+		fmt.Fprintf(r.out, "  → TTL 变更: 修改 (原 TTL: %s)\n", result.OldTTL)
+	case executor.ChangeRemoved:
+		if result.OldTTL != result.NewTTL {
+			fmt.Fprintf(r.out, "  → TTL 变更: 移除 (原 TTL: %s)\n", result.OldTTL)
+		}
+	}
+
+	// 列级 TTL 变更
+	for _, c := range result.Columns {
+		switch {
+		case c.NewTTL == "":
+			fmt.Fprintf(r.out, "  → 列 %s TTL: 移除 (原 TTL: %s)\n", c.Column, c.OldTTL)
+		case c.OldTTL == "":
+			fmt.Fprintf(r.out, "  → 列 %s TTL: 新增\n", c.Column)
+		default:
+			fmt.Fprintf(r.out, "  → 列 %s TTL: 修改 (原 TTL: %s)\n", c.Column, c.OldTTL)
+		}
+	}
+
+	// 逐个分区物化 TTL
+	if len(result.Partitions) > 0 {
+		fmt.Fprintf(r.out, "  → 物化分区: %d 个\n", len(result.Partitions))
 	}
-	fmt.Printf("  ✓ 找到时间字段: %s (%s)\n", result.TimeColumn, timeTypeDesc)
 
 	// Dry-Run 模式或详细模式：显示 SQL
 	if r.dryRun || r.verbose {
-		fmt.Printf("  → SQL: %s\n", result.SQL)
+		fmt.Fprintf(r.out, "  → SQL: %s\n", result.SQL)
+	}
+
+	// 执行前因服务端负载过高而等待
+	if result.Waited > 0 {
+		fmt.Fprintf(r.out, "  ⏸ 服务端负载过高，等待 %.1fs\n", result.Waited.Seconds())
 	}
 
 	// 显示执行结果
 	if result.Success {
 		if r.dryRun {
-			fmt.Printf("  ✓ 预览成功 (未执行)\n")
+			fmt.Fprintf(r.out, "  ✓ 预览成功 (未执行)\n")
+		} else if result.Change == executor.ChangeRemoved {
+			fmt.Fprintf(r.out, "  ✓ TTL 移除成功\n")
+		} else if len(result.Partitions) > 0 {
+			fmt.Fprintf(r.out, "  ✓ TTL 物化成功\n")
 		} else {
-			fmt.Printf("  ✓ TTL 设置成功\n")
+			fmt.Fprintf(r.out, "  ✓ TTL 设置成功\n")
 		}
 	} else if result.Error != nil {
-		fmt.Printf("  ✗ 执行失败: %v\n", result.Error)
+		fmt.Fprintf(r.out, "  ✗ 执行失败: %v\n", result.Error)
+	}
+
+	// --wait 模式下等待到的 mutation 状态
+	if m := result.Mutation; m != nil {
+		switch m.State() {
+		case "done":
+			fmt.Fprintf(r.out, "  ✓ mutation 已完成: %s\n", strings.Join(m.IDs, ", "))
+		case "none":
+			fmt.Fprintf(r.out, "  → 未产生 mutation\n")
+		}
+	}
+
+	// ON CLUSTER 模式下失败或超时的节点
+	for _, host := range result.FailedHosts {
+		fmt.Fprintf(r.out, "    ✗ 节点 %s\n", host)
 	}
 }
 
+// PrintMutationProgress 打印等待中的 mutation 进度
+// 在执行表的工作协程中调用，并发执行时可能与其他表的进度交替出现
+func (r *Reporter) PrintMutationProgress(database, table string, status mutation.Status) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	fmt.Fprintf(r.out, "  … %s.%s: mutation %s 剩余 %d 个 part\n",
+		database, table, strings.Join(status.IDs, ", "), status.PartsToDo)
+}
+
 // PrintSummary 打印执行统计摘要
 func (r *Reporter) PrintSummary() Summary {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	duration := time.Since(r.startTime)
 
 	summary := Summary{
@@ -88,34 +205,80 @@ func (r *Reporter) PrintSummary() Summary {
 	}
 
 	// 统计各状态数量
+	dbIndex := make(map[string]int)
 	for _, result := range r.results {
+		idx, ok := dbIndex[result.Database]
+		if !ok {
+			idx = len(summary.ByDatabase)
+			dbIndex[result.Database] = idx
+			summary.ByDatabase = append(summary.ByDatabase, DatabaseSummary{Database: result.Database})
+		}
+		db := &summary.ByDatabase[idx]
+		db.Total++
+		summary.Waited += result.Waited
+
+		switch result.Change {
+		case executor.ChangeNew:
+			summary.New++
+		case executor.ChangeChanged:
+			summary.Changed++
+		case executor.ChangeUnchanged:
+			summary.Unchanged++
+		case executor.ChangeRemoved:
+			summary.Removed++
+		}
+
 		if result.Skipped {
 			summary.Skipped++
+			db.Skipped++
 		} else if result.Success {
 			summary.Success++
+			db.Success++
 		} else {
 			summary.Failed++
+			db.Failed++
 		}
 	}
 
 	// 打印分隔线
-	fmt.Println("\n" + strings.Repeat("=", 60))
-	fmt.Println("执行总结")
-	fmt.Println(strings.Repeat("=", 60))
+	fmt.Fprintln(r.out, "\n"+strings.Repeat("=", 60))
+	fmt.Fprintln(r.out, "执行总结")
+	fmt.Fprintln(r.out, strings.Repeat("=", 60))
 
 	// 打印统计信息
-	fmt.Printf("\n总表数: %d\n", summary.Total)
-	fmt.Printf("✓ 成功: %d\n", summary.Success)
-	fmt.Printf("✗ 失败: %d\n", summary.Failed)
-	fmt.Printf("⊝ 跳过: %d (无时间字段)\n", summary.Skipped)
-	fmt.Printf("\n执行耗时: %.2fs\n", duration.Seconds())
+	fmt.Fprintf(r.out, "\n总表数: %d\n", summary.Total)
+	fmt.Fprintf(r.out, "✓ 成功: %d\n", summary.Success)
+	fmt.Fprintf(r.out, "✗ 失败: %d\n", summary.Failed)
+	fmt.Fprintf(r.out, "⊝ 跳过: %d\n", summary.Skipped)
+	fmt.Fprintf(r.out, "\nTTL 变更: 新增 %d / 修改 %d / 未变化 %d", summary.New, summary.Changed, summary.Unchanged)
+	if summary.Removed > 0 {
+		fmt.Fprintf(r.out, " / 移除 %d", summary.Removed)
+	}
+	fmt.Fprintln(r.out)
+
+	// 多个数据库时按库列出统计
+	if len(summary.ByDatabase) > 1 {
+		fmt.Fprintln(r.out, "\n按数据库统计:")
+		fmt.Fprintf(r.out, "  %-30s %6s %6s %6s %6s\n", "数据库", "总计", "成功", "失败", "跳过")
+		for _, db := range summary.ByDatabase {
+			fmt.Fprintf(r.out, "  %-30s %6d %6d %6d %6d\n", db.Database, db.Total, db.Success, db.Failed, db.Skipped)
+		}
+	}
+
+	fmt.Fprintf(r.out, "\n执行耗时: %.2fs\n", duration.Seconds())
+	if summary.Waited > 0 {
+		fmt.Fprintf(r.out, "限流等待: %.2fs（各表累计）\n", summary.Waited.Seconds())
+	}
 
 	// 如果有失败的表，列出详情
 	if summary.Failed > 0 {
-		fmt.Println("\n失败的表:")
+		fmt.Fprintln(r.out, "\n失败的表:")
 		for _, result := range r.results {
 			if !result.Success && !result.Skipped {
-				fmt.Printf("  - %s.%s: %v\n", result.Database, result.Table, result.Error)
+				fmt.Fprintf(r.out, "  - %s.%s: %v\n", result.Database, result.Table, result.Error)
+				for _, host := range result.FailedHosts {
+					fmt.Fprintf(r.out, "      节点 %s\n", host)
+				}
 			}
 		}
 	}
@@ -125,5 +288,10 @@ func (r *Reporter) PrintSummary() Summary {
 
 // GetResults 获取所有执行结果
 func (r *Reporter) GetResults() []executor.ExecutionResult {
-	return r.results
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	results := make([]executor.ExecutionResult, len(r.results))
+	copy(results, r.results)
+	return results
 }
@@ -0,0 +1,160 @@
+package reporter
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"clickhouse-ttl-tool/pkg/executor"
+)
+
+// newTestReporter 创建包含成功、失败和跳过结果的报告器，进度输出写入 out
+func newTestReporter(out *bytes.Buffer) *Reporter {
+	r := NewReporter(out, false, false)
+	r.AddResult(executor.ExecutionResult{
+		Database: "logs", Table: "events", Success: true, Change: executor.ChangeNew,
+		NewTTL: "`ts` + INTERVAL 30 DAY", SQL: "ALTER TABLE `logs`.`events` MODIFY TTL `ts` + INTERVAL 30 DAY",
+	})
+	r.AddResult(executor.ExecutionResult{
+		Database: "logs", Table: "metrics", Error: errors.New("timeout"),
+	})
+	r.AddResult(executor.ExecutionResult{
+		Database: "app", Table: "users", Skipped: true, SkipReason: "未找到时间列",
+	})
+	return r
+}
+
+func TestPrintSummary(t *testing.T) {
+	var out bytes.Buffer
+	r := newTestReporter(&out)
+
+	summary := r.PrintSummary()
+	if summary.Total != 3 || summary.Success != 1 || summary.Failed != 1 || summary.Skipped != 1 || summary.New != 1 {
+		t.Errorf("PrintSummary() = %+v", summary)
+	}
+	if len(summary.ByDatabase) != 2 || summary.ByDatabase[0].Database != "logs" || summary.ByDatabase[0].Total != 2 {
+		t.Errorf("ByDatabase = %+v", summary.ByDatabase)
+	}
+	if out.Len() == 0 {
+		t.Error("PrintSummary() wrote nothing to the reporter output")
+	}
+}
+
+func TestExportJSON(t *testing.T) {
+	var out bytes.Buffer
+	r := newTestReporter(&out)
+	summary := r.PrintSummary()
+	out.Reset()
+
+	path := filepath.Join(t.TempDir(), "report.json")
+	if err := r.Export(FormatJSON, path, summary); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("Export() wrote to the progress output: %q", out.String())
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("invalid JSON report: %v", err)
+	}
+	if report.Summary.Total != 3 || len(report.Results) != 3 {
+		t.Fatalf("report = %+v", report)
+	}
+	want := []string{"success", "failed", "skipped"}
+	for i, rec := range report.Results {
+		if rec.Status != want[i] {
+			t.Errorf("Results[%d].Status = %q, want %q", i, rec.Status, want[i])
+		}
+	}
+	if report.Results[1].Error != "timeout" {
+		t.Errorf("Results[1].Error = %q, want timeout", report.Results[1].Error)
+	}
+}
+
+func TestExportCreateError(t *testing.T) {
+	var out bytes.Buffer
+	r := newTestReporter(&out)
+	path := filepath.Join(t.TempDir(), "missing", "report.json")
+	if err := r.Export(FormatJSON, path, Summary{}); err == nil {
+		t.Fatal("Export() error = nil, want error for missing directory")
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	var out bytes.Buffer
+	r := newTestReporter(&out)
+
+	var buf bytes.Buffer
+	if err := r.Write(&buf, FormatCSV, r.PrintSummary()); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "# dry_run=false total=3 ") {
+		t.Errorf("CSV summary line = %q", strings.SplitN(buf.String(), "\n", 2)[0])
+	}
+
+	cr := csv.NewReader(&buf)
+	cr.Comment = '#'
+	rows, err := cr.ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV report: %v", err)
+	}
+	if len(rows) != 4 {
+		t.Fatalf("len(rows) = %d, want header and 3 records", len(rows))
+	}
+	if strings.Join(rows[0], ",") != strings.Join(csvHeader, ",") {
+		t.Errorf("header = %v", rows[0])
+	}
+	for _, row := range rows[1:] {
+		if len(row) != len(csvHeader) {
+			t.Errorf("row has %d columns, want %d", len(row), len(csvHeader))
+		}
+	}
+}
+
+func TestWriteText(t *testing.T) {
+	var out bytes.Buffer
+	r := newTestReporter(&out)
+
+	var buf bytes.Buffer
+	if err := r.Write(&buf, FormatText, r.PrintSummary()); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	want := []string{
+		"logs.events\tsuccess\tALTER TABLE `logs`.`events` MODIFY TTL `ts` + INTERVAL 30 DAY",
+		"logs.metrics\tfailed\ttimeout",
+		"app.users\tskipped\t未找到时间列",
+	}
+	if len(lines) != len(want)+1 {
+		t.Fatalf("text report = %q", buf.String())
+	}
+	for i, w := range want {
+		if lines[i] != w {
+			t.Errorf("line %d = %q, want %q", i, lines[i], w)
+		}
+	}
+	if !strings.HasPrefix(lines[3], "total=3 success=1 failed=1 skipped=1 ") {
+		t.Errorf("summary line = %q", lines[3])
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for _, s := range []string{"text", "JSON", "csv"} {
+		if _, err := ParseFormat(s); err != nil {
+			t.Errorf("ParseFormat(%q) error = %v", s, err)
+		}
+	}
+	if _, err := ParseFormat("xml"); err == nil {
+		t.Error("ParseFormat(\"xml\") error = nil, want error")
+	}
+}
@@ -0,0 +1,164 @@
+// 使用方法：解析 Distributed 表的 engine_full，定位其背后的本地表
+// TTL 只能设置在本地 MergeTree 表上，分布式表本身不存储数据
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DistributedRef 分布式表信息
+type DistributedRef struct {
+	Database string // 分布式表所在数据库
+	Table    string // 分布式表名
+	Cluster  string // engine_full 中指定的集群名
+}
+
+// String 返回 database.table 形式的分布式表名
+func (d *DistributedRef) String() string {
+	return d.Database + "." + d.Table
+}
+
+// parseDistributedEngine 解析 Distributed 引擎参数
+// 例如 Distributed('my_cluster', 'db', 'events_local', rand()) -> my_cluster, db, events_local
+// 数据库参数为空或 currentDatabase() 时使用 defaultDB
+func parseDistributedEngine(engineFull, defaultDB string) (cluster, database, table string, err error) {
+	start := strings.Index(engineFull, "Distributed(")
+	if start < 0 {
+		return "", "", "", errors.New("not a Distributed engine")
+	}
+
+	args, err := splitArgs(engineFull[start+len("Distributed("):])
+	if err != nil {
+		return "", "", "", err
+	}
+	if len(args) < 3 {
+		return "", "", "", fmt.Errorf("invalid Distributed engine: %s", engineFull)
+	}
+
+	cluster = unquote(args[0])
+	database = unquote(args[1])
+	table = unquote(args[2])
+
+	if database == "" || strings.EqualFold(database, "currentDatabase()") {
+		database = defaultDB
+	}
+
+	return cluster, database, table, nil
+}
+
+// splitArgs 按顶层逗号拆分函数参数，直到遇到匹配的右括号
+// 忽略字符串和反引号标识符内部的逗号与括号
+func splitArgs(s string) ([]string, error) {
+	var (
+		args    []string
+		current strings.Builder
+		depth   int
+		quote   rune
+	)
+
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+
+		if quote != 0 {
+			current.WriteRune(c)
+			if c == '\\' && i+1 < len(runes) {
+				i++
+				current.WriteRune(runes[i])
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		}
+
+		switch c {
+		case '\'', '`', '"':
+			quote = c
+			current.WriteRune(c)
+		case '(':
+			depth++
+			current.WriteRune(c)
+		case ')':
+			if depth == 0 {
+				return append(args, strings.TrimSpace(current.String())), nil
+			}
+			depth--
+			current.WriteRune(c)
+		case ',':
+			if depth == 0 {
+				args = append(args, strings.TrimSpace(current.String()))
+				current.Reset()
+			} else {
+				current.WriteRune(c)
+			}
+		default:
+			current.WriteRune(c)
+		}
+	}
+
+	return nil, errors.New("unterminated argument list")
+}
+
+// unquote 去除字符串字面量或标识符的引号
+func unquote(s string) string {
+	if len(s) >= 2 {
+		first, last := s[0], s[len(s)-1]
+		if (first == '\'' || first == '`' || first == '"') && first == last {
+			inner := s[1 : len(s)-1]
+			inner = strings.ReplaceAll(inner, "\\"+string(first), string(first))
+			return strings.ReplaceAll(inner, string(first)+string(first), string(first))
+		}
+	}
+	return s
+}
+
+// resolveDistributed 解析分布式表背后的本地表
+// 返回的 TableInfo 指向本地表，并通过 Distributed 字段记录来源分布式表
+// 本地表在当前节点不存在或不是 MergeTree 表时返回错误
+func (s *Scanner) resolveDistributed(ctx context.Context, database, table, engineFull string) (TableInfo, error) {
+	cluster, localDB, localTable, err := parseDistributedEngine(engineFull, database)
+	if err != nil {
+		return TableInfo{}, err
+	}
+
+	rows, err := s.client.Query(ctx,
+		"SELECT engine, create_table_query, storage_policy, sorting_key, partition_key FROM system.tables WHERE database = ? AND name = ?",
+		localDB, localTable)
+	if err != nil {
+		return TableInfo{}, fmt.Errorf("failed to query local table: %w", err)
+	}
+	if len(rows) == 0 {
+		return TableInfo{}, fmt.Errorf("local table %s.%s of cluster %s does not exist on this node",
+			localDB, localTable, cluster)
+	}
+
+	engine, _ := rows[0]["engine"].(string)
+	createQuery, _ := rows[0]["create_table_query"].(string)
+	storagePolicy, _ := rows[0]["storage_policy"].(string)
+	sortingKey, _ := rows[0]["sorting_key"].(string)
+	partitionKey, _ := rows[0]["partition_key"].(string)
+	if !strings.Contains(engine, "MergeTree") {
+		return TableInfo{}, fmt.Errorf("local table %s.%s has engine %s, not a MergeTree table",
+			localDB, localTable, engine)
+	}
+
+	return TableInfo{
+		Database:      localDB,
+		Table:         localTable,
+		Engine:        engine,
+		CurrentTTL:    extractTableTTL(createQuery),
+		Checksum:      schemaChecksum(createQuery),
+		StoragePolicy: storagePolicy,
+		SortingKey:    sortingKey,
+		PartitionKey:  partitionKey,
+		ColumnTTLs:    extractColumnTTLs(createQuery),
+		Distributed: &DistributedRef{
+			Database: database,
+			Table:    table,
+			Cluster:  cluster,
+		},
+	}, nil
+}
@@ -0,0 +1,62 @@
+package scanner
+
+import "testing"
+
+func TestParseDistributedEngine(t *testing.T) {
+	tests := []struct {
+		name       string
+		engineFull string
+		wantErr    bool
+		cluster    string
+		database   string
+		table      string
+	}{
+		{
+			name:       "sharding key",
+			engineFull: "Distributed('my_cluster', 'logs', 'events_local', rand())",
+			cluster:    "my_cluster", database: "logs", table: "events_local",
+		},
+		{
+			name:       "sharding key with nested call and policy",
+			engineFull: "Distributed('my_cluster', 'logs', 'events_local', cityHash64(user_id, toDate(ts)), 'default')",
+			cluster:    "my_cluster", database: "logs", table: "events_local",
+		},
+		{
+			name:       "current database",
+			engineFull: "Distributed('my_cluster', currentDatabase(), 'events_local')",
+			cluster:    "my_cluster", database: "default_db", table: "events_local",
+		},
+		{
+			name:       "empty database",
+			engineFull: "Distributed('my_cluster', '', 'events_local')",
+			cluster:    "my_cluster", database: "default_db", table: "events_local",
+		},
+		{
+			name:       "settings after engine",
+			engineFull: "Distributed('my_cluster', 'logs', 'events_local') SETTINGS fsync_after_insert = 0",
+			cluster:    "my_cluster", database: "logs", table: "events_local",
+		},
+		{name: "not distributed", engineFull: "MergeTree ORDER BY id", wantErr: true},
+		{name: "too few arguments", engineFull: "Distributed('my_cluster', 'logs')", wantErr: true},
+		{name: "unterminated", engineFull: "Distributed('my_cluster', 'logs', 'events_local'", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster, database, table, err := parseDistributedEngine(tt.engineFull, "default_db")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseDistributedEngine() error = nil, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseDistributedEngine() error = %v", err)
+			}
+			if cluster != tt.cluster || database != tt.database || table != tt.table {
+				t.Errorf("parseDistributedEngine() = %q, %q, %q, want %q, %q, %q",
+					cluster, database, table, tt.cluster, tt.database, tt.table)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+// 使用方法：解析 system.tables 中的 sorting_key 等键表达式
+// 键表达式为逗号分隔的列或表达式列表，如 "host, toStartOfHour(ts)"
+package scanner
+
+import "strings"
+
+// SplitKey 将键表达式按顶层逗号拆分为各个元素
+// system.tables 中的键表达式不带外层括号，如 "host, toStartOfHour(ts)"
+func SplitKey(key string) []string {
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return nil
+	}
+	// splitArgs 以右括号结束，补一个右括号即可复用
+	parts, err := splitArgs(key + ")")
+	if err != nil {
+		return []string{key}
+	}
+	return parts
+}
+
+// HasSortingKeyPrefix 判断 keys 是否为表排序键的前缀
+// 比较时忽略空白和反引号
+func (t TableInfo) HasSortingKeyPrefix(keys []string) bool {
+	sorting := SplitKey(t.SortingKey)
+	if len(keys) > len(sorting) {
+		return false
+	}
+	for i, k := range keys {
+		if normalizeKeyExpr(k) != normalizeKeyExpr(sorting[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeKeyExpr 去除键表达式中的空白和反引号
+func normalizeKeyExpr(expr string) string {
+	var b strings.Builder
+	for _, r := range expr {
+		switch r {
+		case ' ', '\t', '\n', '\r', '`':
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,51 @@
+package scanner
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want []string
+	}{
+		{"", nil},
+		{"ts", []string{"ts"}},
+		{"host, toStartOfHour(ts)", []string{"host", "toStartOfHour(ts)"}},
+		{"tenant_id, tuple(a, b), 'x,y'", []string{"tenant_id", "tuple(a, b)", "'x,y'"}},
+	}
+
+	for _, tt := range tests {
+		if got := SplitKey(tt.key); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SplitKey(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestHasSortingKeyPrefix(t *testing.T) {
+	table := TableInfo{SortingKey: "tenant_id, toStartOfDay(ts), event_id"}
+
+	tests := []struct {
+		keys []string
+		want bool
+	}{
+		{[]string{"tenant_id"}, true},
+		{[]string{"tenant_id", "toStartOfDay(ts)"}, true},
+		{[]string{"`tenant_id`", "toStartOfDay( ts )"}, true},
+		{[]string{"tenant_id", "toStartOfDay(ts)", "event_id"}, true},
+		{[]string{"toStartOfDay(ts)"}, false},
+		{[]string{"tenant_id", "ts"}, false},
+		{[]string{"tenant_id", "toStartOfDay(ts)", "event_id", "extra"}, false},
+	}
+
+	for _, tt := range tests {
+		if got := table.HasSortingKeyPrefix(tt.keys); got != tt.want {
+			t.Errorf("HasSortingKeyPrefix(%q) = %v, want %v", tt.keys, got, tt.want)
+		}
+	}
+
+	if (TableInfo{}).HasSortingKeyPrefix([]string{"ts"}) {
+		t.Error("HasSortingKeyPrefix() on a table without sorting key = true, want false")
+	}
+}
@@ -0,0 +1,40 @@
+// 使用方法：查询表的活跃数据分区
+// 供 materialize 子命令逐个分区执行 MATERIALIZE TTL
+package scanner
+
+import (
+	"context"
+	"fmt"
+
+	"clickhouse-ttl-tool/pkg/utils"
+)
+
+// Partitions 返回表中活跃 part 所在的分区 ID（按分区 ID 排序）
+// cluster 非空时通过 clusterAllReplicas 汇总集群所有节点的分区
+func (s *Scanner) Partitions(ctx context.Context, database, table, cluster string) ([]string, error) {
+	source := "system.parts"
+	if cluster != "" {
+		source = fmt.Sprintf("clusterAllReplicas(%s, system.parts)", utils.EscapeString(cluster))
+	}
+
+	query := fmt.Sprintf(`
+		SELECT DISTINCT partition_id
+		FROM %s
+		WHERE database = ? AND table = ? AND active
+		ORDER BY partition_id
+	`, source)
+
+	rows, err := s.client.Query(ctx, query, database, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query partitions: %w", err)
+	}
+
+	partitions := make([]string, 0, len(rows))
+	for _, row := range rows {
+		if id, ok := row["partition_id"].(string); ok {
+			partitions = append(partitions, id)
+		}
+	}
+
+	return partitions, nil
+}
@@ -1,18 +1,54 @@
 // 使用方法：扫描 ClickHouse 数据库中的所有用户表
 // 过滤系统表和视图，仅返回物理数据表
+// Distributed 表会被解析为其背后的本地表
 package scanner
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io"
+	"os"
 	"strings"
 
 	"clickhouse-ttl-tool/pkg/client"
+	"clickhouse-ttl-tool/pkg/detector"
+	"clickhouse-ttl-tool/pkg/matcher"
 )
 
+// systemDatabases 系统数据库，扫描时始终跳过
+var systemDatabases = map[string]bool{
+	"system":             true,
+	"INFORMATION_SCHEMA": true,
+	"information_schema": true,
+}
+
 // Scanner 表扫描器
 type Scanner struct {
-	client *client.Client
+	client     *client.Client
+	filter     Filter
+	candidates detector.Candidates
+	out        io.Writer // 扫描警告的输出目标
+}
+
+// Filter 表名过滤条件
+type Filter struct {
+	Include []matcher.Pattern // 为空时包含所有表
+	Exclude []matcher.Pattern // 优先于 Include
+}
+
+// Allow 判断表是否通过过滤
+// 模式同时与表名和 database.table 形式的完整表名比较
+func (f Filter) Allow(database, table string) bool {
+	qualified := database + "." + table
+	if matcher.MatchAny(f.Exclude, table) || matcher.MatchAny(f.Exclude, qualified) {
+		return false
+	}
+	if len(f.Include) == 0 {
+		return true
+	}
+	return matcher.MatchAny(f.Include, table) || matcher.MatchAny(f.Include, qualified)
 }
 
 // TableInfo 表信息
@@ -21,13 +57,86 @@ type TableInfo struct {
 	Table       string   // 表名
 	Engine      string   // 引擎类型
 	TimeColumns []string // 时间类型列名（用于 TTL）
+	CurrentTTL  string   // 当前的表级 TTL 表达式，没有时为空
+	Checksum    string   // 表结构校验和（基于 create_table_query），用于检测结构变化
+	// 存储策略名（system.tables.storage_policy），非 MergeTree 表为空
+	StoragePolicy string
+	SortingKey    string // 排序键表达式（system.tables.sorting_key）
+	PartitionKey  string // 分区键表达式（system.tables.partition_key），未分区时为空
+	// 当前的列级 TTL（列名到 TTL 表达式），没有列级 TTL 的列不出现
+	ColumnTTLs map[string]string
+	// 通过分布式表解析得到本地表时，记录来源分布式表
+	Distributed *DistributedRef
 }
 
 // NewScanner 创建新的扫描器
-func NewScanner(client *client.Client) *Scanner {
+// candidates 为时间字段候选，决定 TableInfo.TimeColumns 的内容和顺序
+func NewScanner(client *client.Client, filter Filter, candidates detector.Candidates) *Scanner {
 	return &Scanner{
-		client: client,
+		client:     client,
+		filter:     filter,
+		candidates: candidates,
+		out:        os.Stdout,
+	}
+}
+
+// WithOutput 返回将扫描警告输出到 w 的扫描器副本
+func (s *Scanner) WithOutput(w io.Writer) *Scanner {
+	c := *s
+	c.out = w
+	return &c
+}
+
+// ResolveDatabases 将数据库名或匹配模式解析为实际存在的数据库列表
+// all 为 true 时返回所有非系统数据库
+func (s *Scanner) ResolveDatabases(ctx context.Context, patterns []matcher.Pattern, all bool) ([]string, error) {
+	rows, err := s.client.Query(ctx, "SELECT name FROM system.databases ORDER BY name")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list databases: %w", err)
+	}
+
+	var databases []string
+	for _, row := range rows {
+		name, ok := row["name"].(string)
+		if !ok || systemDatabases[name] {
+			continue
+		}
+		if all || matcher.MatchAny(patterns, name) {
+			databases = append(databases, name)
+		}
 	}
+
+	// 精确指定但不存在的数据库直接报错，避免拼写错误被静默忽略
+	for _, p := range patterns {
+		if p.Kind != matcher.KindExact {
+			continue
+		}
+		found := false
+		for _, db := range databases {
+			if db == p.Value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("database %s not found", p.Value)
+		}
+	}
+
+	return databases, nil
+}
+
+// ScanDatabases 依次扫描多个数据库的所有用户表
+func (s *Scanner) ScanDatabases(ctx context.Context, databases []string) ([]TableInfo, error) {
+	var tables []TableInfo
+	for _, db := range databases {
+		dbTables, err := s.ScanTables(ctx, db)
+		if err != nil {
+			return nil, fmt.Errorf("database %s: %w", db, err)
+		}
+		tables = append(tables, dbTables...)
+	}
+	return mergeTables(tables), nil
 }
 
 // ScanTables 扫描指定数据库的所有用户表
@@ -36,11 +145,16 @@ func (s *Scanner) ScanTables(ctx context.Context, database string) ([]TableInfo,
 		SELECT
 			database,
 			name as table,
-			engine
+			engine,
+			engine_full,
+			create_table_query,
+			storage_policy,
+			sorting_key,
+			partition_key
 		FROM system.tables
 		WHERE database = ?
 		  AND database NOT IN ('system', 'INFORMATION_SCHEMA', 'information_schema')
-		  AND engine NOT IN ('View', 'MaterializedView', 'Dictionary', 'Distributed')
+		  AND engine NOT IN ('View', 'MaterializedView', 'Dictionary')
 		ORDER BY name
 	`
 
@@ -71,46 +185,126 @@ func (s *Scanner) ScanTables(ctx context.Context, database string) ([]TableInfo,
 			continue
 		}
 
+		// 按 include/exclude 过滤，被排除的表不扫描列也不出现在报告中
+		if !s.filter.Allow(db, table) {
+			continue
+		}
+
+		createQuery, _ := row["create_table_query"].(string)
+		storagePolicy, _ := row["storage_policy"].(string)
+		sortingKey, _ := row["sorting_key"].(string)
+		partitionKey, _ := row["partition_key"].(string)
+		info := TableInfo{
+			Database:      db,
+			Table:         table,
+			Engine:        engine,
+			CurrentTTL:    extractTableTTL(createQuery),
+			Checksum:      schemaChecksum(createQuery),
+			StoragePolicy: storagePolicy,
+			SortingKey:    sortingKey,
+			PartitionKey:  partitionKey,
+			ColumnTTLs:    extractColumnTTLs(createQuery),
+		}
+
+		// 分布式表：TTL 需要设置在背后的本地表上
+		if engine == "Distributed" {
+			engineFull, _ := row["engine_full"].(string)
+			info, err = s.resolveDistributed(ctx, db, table, engineFull)
+			if err != nil {
+				// 记录错误但不中断扫描
+				fmt.Fprintf(s.out, "警告: 解析分布式表 %s.%s 失败: %v\n", db, table, err)
+				continue
+			}
+			// 本地表被显式排除时同样跳过
+			if matcher.MatchAny(s.filter.Exclude, info.Table) ||
+				matcher.MatchAny(s.filter.Exclude, info.Database+"."+info.Table) {
+				continue
+			}
+		}
+
 		// 扫描表中的时间列
-		timeColumns, err := s.scanTimeColumns(ctx, db, table)
+		timeColumns, err := s.scanTimeColumns(ctx, info.Database, info.Table)
 		if err != nil {
 			// 记录错误但不中断扫描
-			fmt.Printf("警告: 扫描表 %s.%s 的时间列失败: %v\n", db, table, err)
+			fmt.Fprintf(s.out, "警告: 扫描表 %s.%s 的时间列失败: %v\n", info.Database, info.Table, err)
 			timeColumns = []string{}
 		}
+		info.TimeColumns = timeColumns
 
-		tables = append(tables, TableInfo{
-			Database:    db,
-			Table:       table,
-			Engine:      engine,
-			TimeColumns: timeColumns,
-		})
+		tables = append(tables, info)
 	}
 
-	return tables, nil
+	return mergeTables(tables), nil
+}
+
+// Checksum 查询表当前的结构校验和
+func (s *Scanner) Checksum(ctx context.Context, database, table string) (string, error) {
+	info, err := s.Table(ctx, database, table)
+	if err != nil {
+		return "", err
+	}
+	return info.Checksum, nil
 }
 
-// scanTimeColumns 扫描指定表的时间类型列
-// 包括 Date/DateTime 类型和常见时间戳字段名的 UInt64 类型
+// Table 查询单个表当前的 TTL 和结构校验和（不扫描时间列，不解析分布式表）
+func (s *Scanner) Table(ctx context.Context, database, table string) (TableInfo, error) {
+	rows, err := s.client.Query(ctx,
+		"SELECT engine, create_table_query FROM system.tables WHERE database = ? AND name = ?",
+		database, table)
+	if err != nil {
+		return TableInfo{}, fmt.Errorf("failed to query table schema: %w", err)
+	}
+	if len(rows) == 0 {
+		return TableInfo{}, fmt.Errorf("table %s.%s does not exist", database, table)
+	}
+
+	engine, _ := rows[0]["engine"].(string)
+	createQuery, _ := rows[0]["create_table_query"].(string)
+	return TableInfo{
+		Database:   database,
+		Table:      table,
+		Engine:     engine,
+		CurrentTTL: extractTableTTL(createQuery),
+		Checksum:   schemaChecksum(createQuery),
+		ColumnTTLs: extractColumnTTLs(createQuery),
+	}, nil
+}
+
+// schemaChecksum 计算建表语句的 SHA-256 校验和
+// 建表语句包含列定义、引擎参数和 TTL，任一变化都会导致校验和变化
+func schemaChecksum(createQuery string) string {
+	sum := sha256.Sum256([]byte(createQuery))
+	return hex.EncodeToString(sum[:])
+}
+
+// mergeTables 按 database.table 去重，保留首次出现的位置
+// 本地表既被直接扫描又被分布式表引用时只处理一次，并保留分布式表映射
+func mergeTables(tables []TableInfo) []TableInfo {
+	index := make(map[string]int)
+	merged := make([]TableInfo, 0, len(tables))
+	for _, t := range tables {
+		key := t.Database + "." + t.Table
+		if i, ok := index[key]; ok {
+			if merged[i].Distributed == nil {
+				merged[i].Distributed = t.Distributed
+			}
+			continue
+		}
+		index[key] = len(merged)
+		merged = append(merged, t)
+	}
+	return merged
+}
+
+// scanTimeColumns 扫描指定表可以作为时间字段的列，按候选优先级排序
+// 包括 Date/DateTime 类型（含 Nullable / LowCardinality 包装）和匹配候选的整数、文本类型
 func (s *Scanner) scanTimeColumns(ctx context.Context, database, table string) ([]string, error) {
+	// 类型可能带 Nullable / LowCardinality 包装，查询全部列后在本地解析类型
 	query := `
 		SELECT name, type
 		FROM system.columns
 		WHERE database = ?
 		  AND table = ?
-		  AND (
-			  type LIKE 'Date%'
-			  OR type LIKE 'DateTime%'
-			  OR (
-				  type LIKE 'UInt64%'
-				  AND (
-					  name = 'timestamp'
-					  OR name = 'event_time'
-					  OR name = 'created_at'
-					  OR name = 'time'
-				  )
-			  )
-		  )
 		ORDER BY position
 	`
 
@@ -119,16 +313,16 @@ func (s *Scanner) scanTimeColumns(ctx context.Context, database, table string) (
 		return nil, fmt.Errorf("query time columns failed: %w", err)
 	}
 
-	var columns []string
-	seen := make(map[string]bool) // 去重
+	columns := make([]detector.Column, 0, len(rows))
 	for _, row := range rows {
 		name, ok := row["name"].(string)
-		if !ok || seen[name] {
+		if !ok {
 			continue
 		}
-		columns = append(columns, name)
-		seen[name] = true
+		colType, _ := row["type"].(string)
+		columns = append(columns, detector.Column{Name: name, Type: colType})
 	}
 
-	return columns, nil
+	// 按候选列表排序，整数和文本列只有匹配候选时才视为时间列
+	return s.candidates.Rank(columns), nil
 }
@@ -0,0 +1,42 @@
+package scanner
+
+import (
+	"testing"
+
+	"clickhouse-ttl-tool/pkg/matcher"
+)
+
+func TestFilterAllow(t *testing.T) {
+	mustParse := func(values ...string) []matcher.Pattern {
+		t.Helper()
+		patterns, err := matcher.ParseAll(values)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return patterns
+	}
+
+	tests := []struct {
+		name            string
+		filter          Filter
+		database, table string
+		want            bool
+	}{
+		{"empty filter", Filter{}, "db", "events", true},
+		{"include by table", Filter{Include: mustParse("event*")}, "db", "events", true},
+		{"include miss", Filter{Include: mustParse("event*")}, "db", "metrics", false},
+		{"include qualified", Filter{Include: mustParse("db.events")}, "db", "events", true},
+		{"include qualified other db", Filter{Include: mustParse("db.events")}, "other", "events", false},
+		{"exclude by table", Filter{Exclude: mustParse("tmp_*")}, "db", "tmp_x", false},
+		{"exclude qualified", Filter{Exclude: mustParse("re:^db\\.tmp_")}, "db", "tmp_x", false},
+		{"exclude wins", Filter{Include: mustParse("*"), Exclude: mustParse("events")}, "db", "events", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Allow(tt.database, tt.table); got != tt.want {
+				t.Errorf("Allow(%q, %q) = %v, want %v", tt.database, tt.table, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+// 使用方法：读取 system.storage_policies 中的存储策略
+// 用于校验 TTL TO DISK / TO VOLUME 引用的磁盘和卷是否属于表的存储策略
+package scanner
+
+import (
+	"context"
+	"fmt"
+)
+
+// StoragePolicy 存储策略
+type StoragePolicy struct {
+	Name    string              // 策略名
+	Volumes map[string][]string // 卷名到磁盘列表的映射
+}
+
+// HasVolume 判断策略是否包含指定的卷
+func (p *StoragePolicy) HasVolume(name string) bool {
+	_, ok := p.Volumes[name]
+	return ok
+}
+
+// HasDisk 判断策略的任一卷是否包含指定的磁盘
+func (p *StoragePolicy) HasDisk(name string) bool {
+	for _, disks := range p.Volumes {
+		for _, d := range disks {
+			if d == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// StoragePolicies 查询所有存储策略，返回策略名到策略的映射
+func (s *Scanner) StoragePolicies(ctx context.Context) (map[string]*StoragePolicy, error) {
+	// 展开 disks 数组，避免扫描 Array 类型
+	query := `
+		SELECT
+			policy_name,
+			volume_name,
+			arrayJoin(disks) AS disk
+		FROM system.storage_policies
+		ORDER BY policy_name, volume_priority
+	`
+
+	rows, err := s.client.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query storage policies: %w", err)
+	}
+
+	policies := make(map[string]*StoragePolicy)
+	for _, row := range rows {
+		name, ok := row["policy_name"].(string)
+		if !ok {
+			continue
+		}
+		volume, _ := row["volume_name"].(string)
+		disk, _ := row["disk"].(string)
+
+		p, ok := policies[name]
+		if !ok {
+			p = &StoragePolicy{Name: name, Volumes: make(map[string][]string)}
+			policies[name] = p
+		}
+		p.Volumes[volume] = append(p.Volumes[volume], disk)
+	}
+
+	return policies, nil
+}
@@ -0,0 +1,173 @@
+// 使用方法：从 system.tables.create_table_query 中提取表级和列级 TTL 子句
+// 列级 TTL 位于列定义的括号内，不会被当作表级 TTL
+package scanner
+
+import (
+	"strings"
+	"unicode"
+)
+
+// ttlEndKeywords 表级 TTL 子句之后可能出现的子句关键字
+var ttlEndKeywords = []string{"SETTINGS", "COMMENT"}
+
+// extractTableTTL 提取建表语句中的表级 TTL 表达式（不含 TTL 关键字）
+// 没有表级 TTL 时返回空字符串
+func extractTableTTL(createQuery string) string {
+	start := findTopLevelKeyword(createQuery, 0, "TTL")
+	if start < 0 {
+		return ""
+	}
+	start += len("TTL")
+
+	end := len(createQuery)
+	for _, kw := range ttlEndKeywords {
+		if i := findTopLevelKeyword(createQuery, start, kw); i >= 0 && i < end {
+			end = i
+		}
+	}
+
+	return strings.TrimSpace(createQuery[start:end])
+}
+
+// columnDefSkipKeywords 列定义括号内非列定义项的起始关键字
+var columnDefSkipKeywords = []string{"INDEX", "PROJECTION", "CONSTRAINT", "PRIMARY"}
+
+// extractColumnTTLs 提取建表语句中各列的 TTL 表达式（不含 TTL 关键字）
+// 返回列名到 TTL 表达式的映射，没有列级 TTL 的列不出现在结果中
+func extractColumnTTLs(createQuery string) map[string]string {
+	ttls := make(map[string]string)
+
+	open := findTopLevelByte(createQuery, '(')
+	if open < 0 {
+		return ttls
+	}
+	defs, err := splitArgs(createQuery[open+1:])
+	if err != nil {
+		return ttls
+	}
+
+	for _, def := range defs {
+		name, rest := splitColumnName(def)
+		if name == "" {
+			continue
+		}
+
+		start := findTopLevelKeyword(rest, 0, "TTL")
+		if start < 0 {
+			continue
+		}
+		start += len("TTL")
+
+		end := len(rest)
+		if i := findTopLevelKeyword(rest, start, "SETTINGS"); i >= 0 {
+			end = i
+		}
+		ttls[name] = strings.TrimSpace(rest[start:end])
+	}
+
+	return ttls
+}
+
+// splitColumnName 拆分列定义中的列名和其余部分
+// 索引、投影、约束等非列定义项返回空列名
+func splitColumnName(def string) (string, string) {
+	def = strings.TrimSpace(def)
+	if def == "" {
+		return "", ""
+	}
+
+	if def[0] == '`' || def[0] == '"' {
+		for i := 1; i < len(def); i++ {
+			if def[i] == '\\' {
+				i++
+			} else if def[i] == def[0] {
+				return unquote(def[:i+1]), def[i+1:]
+			}
+		}
+		return "", ""
+	}
+
+	for _, kw := range columnDefSkipKeywords {
+		if hasKeywordAt(def, 0, kw) {
+			return "", ""
+		}
+	}
+
+	name, rest, _ := strings.Cut(def, " ")
+	return name, rest
+}
+
+// findTopLevelByte 查找位于引号之外的首个指定字符，未找到返回 -1
+func findTopLevelByte(s string, b byte) int {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if quote != 0 {
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		switch c {
+		case '\'', '`', '"':
+			quote = c
+		case b:
+			return i
+		}
+	}
+	return -1
+}
+
+// findTopLevelKeyword 从 from 开始查找位于括号和引号之外的独立关键字
+// 返回关键字起始位置，未找到返回 -1
+func findTopLevelKeyword(s string, from int, keyword string) int {
+	depth := 0
+	var quote byte
+
+	for i := from; i < len(s); i++ {
+		c := s[i]
+
+		if quote != 0 {
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		}
+
+		switch c {
+		case '\'', '`', '"':
+			quote = c
+		case '(':
+			depth++
+		case ')':
+			depth--
+		default:
+			if depth == 0 && hasKeywordAt(s, i, keyword) {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
+
+// hasKeywordAt 判断 s 在位置 i 处是否为独立的关键字（前后不是标识符字符）
+func hasKeywordAt(s string, i int, keyword string) bool {
+	if !strings.HasPrefix(s[i:], keyword) {
+		return false
+	}
+	if i > 0 && isIdentChar(rune(s[i-1])) {
+		return false
+	}
+	end := i + len(keyword)
+	return end >= len(s) || !isIdentChar(rune(s[end]))
+}
+
+// isIdentChar 判断字符是否可以出现在标识符中
+func isIdentChar(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,78 @@
+package scanner
+
+import (
+	"reflect"
+	"testing"
+)
+
+// 建表语句取自 system.tables.create_table_query 的实际输出
+const (
+	createWithTTL = "CREATE TABLE logs.events (`event_time` DateTime, `user_id` UInt64, " +
+		"`ip` String TTL event_time + toIntervalDay(30), " +
+		"`payload` String CODEC(ZSTD(3)) TTL event_time + toIntervalDay(7), " +
+		"`note` String DEFAULT 'TTL 30 DAY', " +
+		"INDEX idx_user user_id TYPE minmax GRANULARITY 1) " +
+		"ENGINE = MergeTree PARTITION BY toYYYYMM(event_time) ORDER BY (user_id, event_time) " +
+		"TTL event_time + toIntervalDay(90) SETTINGS index_granularity = 8192"
+	createWithWhere = "CREATE TABLE logs.app_log (`ts` DateTime, `level` LowCardinality(String), `msg` String) " +
+		"ENGINE = MergeTree ORDER BY ts " +
+		"TTL ts + toIntervalDay(3) WHERE (level = 'debug') AND (msg != 'TTL'), ts + toIntervalDay(90) " +
+		"SETTINGS index_granularity = 8192 COMMENT 'application log'"
+	createWithoutTTL = "CREATE TABLE logs.users (`id` UInt64, `name` String) " +
+		"ENGINE = ReplacingMergeTree ORDER BY id SETTINGS index_granularity = 8192"
+	createQuotedColumn = "CREATE TABLE logs.quoted (`ts` DateTime, `user ttl` String TTL ts + toIntervalDay(1)) " +
+		"ENGINE = MergeTree ORDER BY ts"
+)
+
+func TestExtractTableTTL(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"settings after ttl", createWithTTL, "event_time + toIntervalDay(90)"},
+		{"where and comment", createWithWhere, "ts + toIntervalDay(3) WHERE (level = 'debug') AND (msg != 'TTL'), ts + toIntervalDay(90)"},
+		{"no ttl", createWithoutTTL, ""},
+		{"column ttl only", createQuotedColumn, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := extractTableTTL(tt.query); got != tt.want {
+				t.Errorf("extractTableTTL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractColumnTTLs(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  map[string]string
+	}{
+		{
+			name:  "column ttls with codec and index",
+			query: createWithTTL,
+			want: map[string]string{
+				"ip":      "event_time + toIntervalDay(30)",
+				"payload": "event_time + toIntervalDay(7)",
+			},
+		},
+		{
+			name:  "quoted column name",
+			query: createQuotedColumn,
+			want:  map[string]string{"user ttl": "ts + toIntervalDay(1)"},
+		},
+		{"table ttl only", createWithWhere, map[string]string{}},
+		{"no ttl", createWithoutTTL, map[string]string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := extractColumnTTLs(tt.query); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("extractColumnTTLs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,247 @@
+// 使用方法：在本地状态文件中记录每次运行修改前的 TTL
+// 每次运行对应一个 JSON 文件（<state-dir>/<run-id>.json），供 rollback 子命令恢复
+package state
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// runIDLayout 运行 ID 的时间格式，之后附加随机后缀，避免同一秒内启动的运行使用相同的 ID
+const runIDLayout = "20060102-150405"
+
+// Status 表的执行结果
+type Status string
+
+const (
+	// StatusPending 已记录，ALTER 尚未返回（进程中断时保持该状态）
+	StatusPending Status = "pending"
+	// StatusApplied ALTER 执行成功
+	StatusApplied Status = "applied"
+	// StatusPartial ON CLUSTER 模式下 ALTER 已在部分节点执行，其余节点失败、超时或状态未知
+	StatusPartial Status = "partial"
+	// StatusFailed ALTER 执行失败，表的 TTL 未被修改
+	StatusFailed Status = "failed"
+)
+
+// Entry 单个表修改前的 TTL 记录
+// 表级 TTL 未修改时 OldTTL 与 NewTTL 相同
+type Entry struct {
+	Database   string      `json:"database"`          // 数据库名
+	Table      string      `json:"table"`             // 表名
+	OldTTL     string      `json:"old_ttl"`           // 修改前的 TTL 表达式，为空表示原本没有 TTL
+	NewTTL     string      `json:"new_ttl"`           // 本次设置的 TTL 表达式
+	Columns    []ColumnTTL `json:"columns,omitempty"` // 本次修改的列级 TTL
+	SQL        string      `json:"sql"`               // 本次执行的 SQL
+	RecordedAt time.Time   `json:"recorded_at"`       // 记录时间（执行 ALTER 之前）
+	Status     Status      `json:"status,omitempty"`  // 执行结果，旧版本的记录为空
+	Error      string      `json:"error,omitempty"`   // 执行失败的原因
+}
+
+// Applied 判断记录的 ALTER 是否已在至少一个节点执行，这些表需要回滚
+// 旧版本的记录没有执行结果，视为执行成功
+func (e Entry) Applied() bool {
+	return e.Status == "" || e.Status == StatusApplied || e.Status == StatusPartial
+}
+
+// ColumnTTL 单个列的 TTL 修改记录
+type ColumnTTL struct {
+	Column string `json:"column"`  // 列名
+	OldTTL string `json:"old_ttl"` // 修改前的列级 TTL，为空表示原本没有
+	NewTTL string `json:"new_ttl"` // 本次设置的列级 TTL，为空表示移除
+}
+
+// Run 单次运行的状态
+type Run struct {
+	ID        string    `json:"id"`                // 运行 ID
+	StartedAt time.Time `json:"started_at"`        // 开始时间
+	Cluster   string    `json:"cluster,omitempty"` // ON CLUSTER 集群名
+	Entries   []Entry   `json:"entries"`           // 按执行顺序记录的表
+}
+
+// Store 状态文件目录
+type Store struct {
+	dir string
+}
+
+// NewStore 创建状态存储
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// DefaultDir 返回默认的状态目录（~/.clickhouse-ttl-tool/runs）
+func DefaultDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".clickhouse-ttl-tool", "runs")
+	}
+	return filepath.Join(home, ".clickhouse-ttl-tool", "runs")
+}
+
+// NewRunID 生成基于当前时间和随机后缀的运行 ID，如 20240102-150405-a1b2c3
+func NewRunID() string {
+	suffix := make([]byte, 3)
+	if _, err := rand.Read(suffix); err != nil {
+		// 随机数不可用时退化为纳秒，仍能区分同一秒内的运行
+		return fmt.Sprintf("%s-%09d", time.Now().Format(runIDLayout), time.Now().Nanosecond())
+	}
+	return time.Now().Format(runIDLayout) + "-" + hex.EncodeToString(suffix)
+}
+
+// path 返回运行对应的状态文件路径
+func (s *Store) path(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
+
+// Create 创建新运行的状态文件，同名文件已存在时返回错误，避免覆盖其他运行的记录
+func (s *Store) Create(run *Run) error {
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create state dir: %w", err)
+	}
+
+	f, err := os.OpenFile(s.path(run.ID), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return fmt.Errorf("run %s already exists in %s", run.ID, s.dir)
+		}
+		return fmt.Errorf("failed to create state file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to create state file: %w", err)
+	}
+
+	return s.Save(run)
+}
+
+// Save 保存运行状态，先写临时文件再重命名，避免中断时留下不完整的文件
+func (s *Store) Save(run *Run) error {
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create state dir: %w", err)
+	}
+
+	data, err := json.MarshalIndent(run, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+
+	tmp := s.path(run.ID) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if err := os.Rename(tmp, s.path(run.ID)); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+
+	return nil
+}
+
+// Load 加载指定运行的状态
+func (s *Store) Load(id string) (*Run, error) {
+	data, err := os.ReadFile(s.path(id))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("run %s not found in %s", id, s.dir)
+		}
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+
+	var run Run
+	if err := json.Unmarshal(data, &run); err != nil {
+		return nil, fmt.Errorf("failed to decode state file: %w", err)
+	}
+
+	return &run, nil
+}
+
+// List 按时间倒序列出所有运行 ID
+func (s *Store) List() ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list state files: %w", err)
+	}
+
+	ids := make([]string, 0, len(files))
+	for _, f := range files {
+		ids = append(ids, filepath.Base(f[:len(f)-len(".json")]))
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(ids)))
+
+	return ids, nil
+}
+
+// Recorder 记录单次运行的状态，每条记录写入后立即落盘
+// 可被多个 goroutine 并发调用
+type Recorder struct {
+	store   *Store
+	run     *Run
+	created bool // 状态文件是否已创建
+	mu      sync.Mutex
+}
+
+// NewRecorder 为新的运行创建记录器
+func NewRecorder(store *Store, id, cluster string) *Recorder {
+	return &Recorder{
+		store: store,
+		run: &Run{
+			ID:        id,
+			StartedAt: time.Now(),
+			Cluster:   cluster,
+			Entries:   []Entry{},
+		},
+	}
+}
+
+// RunID 返回运行 ID
+func (r *Recorder) RunID() string {
+	return r.run.ID
+}
+
+// Record 追加一条待执行的记录并保存，返回记录的序号，执行结束后通过 Finish 更新结果
+// 首次记录时创建状态文件，同名文件已存在时返回错误
+func (r *Recorder) Record(entry Entry) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry.RecordedAt = time.Now()
+	entry.Status = StatusPending
+	r.run.Entries = append(r.run.Entries, entry)
+	index := len(r.run.Entries) - 1
+
+	if !r.created {
+		if err := r.store.Create(r.run); err != nil {
+			r.run.Entries = r.run.Entries[:index]
+			return 0, err
+		}
+		r.created = true
+		return index, nil
+	}
+	return index, r.store.Save(r.run)
+}
+
+// Finish 记录 ALTER 的执行结果并保存，err 为执行失败（或部分节点失败）的原因
+func (r *Recorder) Finish(index int, status Status, err error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry := &r.run.Entries[index]
+	entry.Status = status
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	return r.store.Save(r.run)
+}
+
+// Count 返回已记录的表数
+func (r *Recorder) Count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.run.Entries)
+}
@@ -0,0 +1,131 @@
+package state
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestRecorder(t *testing.T) {
+	store := NewStore(t.TempDir())
+	rec := NewRecorder(store, "20240101-120000-a1b2c3", "my_cluster")
+
+	applied, err := rec.Record(Entry{Database: "logs", Table: "events", NewTTL: "ts + INTERVAL 30 DAY"})
+	if err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	partial, err := rec.Record(Entry{Database: "logs", Table: "app_log", NewTTL: "ts + INTERVAL 7 DAY"})
+	if err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	failed, err := rec.Record(Entry{Database: "logs", Table: "users", NewTTL: "ts + INTERVAL 1 DAY"})
+	if err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+
+	// 进程在 ALTER 返回前中断时，记录保持 pending
+	run, err := store.Load(rec.RunID())
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(run.Entries) != 3 || run.Entries[0].Status != StatusPending {
+		t.Fatalf("entries before Finish = %+v, want 3 pending entries", run.Entries)
+	}
+
+	if err := rec.Finish(applied, StatusApplied, nil); err != nil {
+		t.Fatalf("Finish() error = %v", err)
+	}
+	if err := rec.Finish(partial, StatusPartial, errors.New("TTL failed on 1/2 hosts")); err != nil {
+		t.Fatalf("Finish() error = %v", err)
+	}
+	if err := rec.Finish(failed, StatusFailed, errors.New("code 36")); err != nil {
+		t.Fatalf("Finish() error = %v", err)
+	}
+
+	run, err = store.Load(rec.RunID())
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if run.Cluster != "my_cluster" {
+		t.Errorf("Cluster = %q, want my_cluster", run.Cluster)
+	}
+	want := []struct {
+		status  Status
+		applied bool
+		err     string
+	}{
+		{StatusApplied, true, ""},
+		{StatusPartial, true, "TTL failed on 1/2 hosts"},
+		{StatusFailed, false, "code 36"},
+	}
+	for i, w := range want {
+		e := run.Entries[i]
+		if e.Status != w.status || e.Applied() != w.applied || e.Error != w.err {
+			t.Errorf("entry %d = {%s applied=%v %q}, want {%s applied=%v %q}",
+				i, e.Status, e.Applied(), e.Error, w.status, w.applied, w.err)
+		}
+	}
+}
+
+func TestRecorderDoesNotOverwriteRun(t *testing.T) {
+	store := NewStore(t.TempDir())
+	if _, err := NewRecorder(store, "same", "").Record(Entry{Table: "a"}); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+
+	_, err := NewRecorder(store, "same", "").Record(Entry{Table: "b"})
+	if err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("Record() error = %v, want already exists", err)
+	}
+
+	run, err := store.Load("same")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(run.Entries) != 1 || run.Entries[0].Table != "a" {
+		t.Errorf("entries = %+v, want the first run's entry only", run.Entries)
+	}
+}
+
+func TestLegacyEntryApplied(t *testing.T) {
+	if !(Entry{}).Applied() {
+		t.Error("entry without status should be treated as applied")
+	}
+	if (Entry{Status: StatusPending}).Applied() {
+		t.Error("pending entry should not be treated as applied")
+	}
+}
+
+func TestNewRunID(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		id := NewRunID()
+		if seen[id] {
+			t.Fatalf("NewRunID() returned duplicate %q", id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestStoreList(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStore(dir)
+	for _, id := range []string{"20240101-120000-aaaaaa", "20240301-120000-bbbbbb", "20240201-120000-cccccc"} {
+		if err := store.Create(&Run{ID: id}); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+	if _, err := os.Stat(dir + "/20240101-120000-aaaaaa.json.tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary file left behind: %v", err)
+	}
+
+	ids, err := store.List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	want := "20240301-120000-bbbbbb,20240201-120000-cccccc,20240101-120000-aaaaaa"
+	if got := strings.Join(ids, ","); got != want {
+		t.Errorf("List() = %s, want %s", got, want)
+	}
+}
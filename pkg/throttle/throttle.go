@@ -0,0 +1,202 @@
+// 使用方法：根据服务端后台合并和 mutation 负载决定是否暂停执行 ALTER
+// 通过 system.merges、system.mutations、system.metrics 和 system.asynchronous_metrics 获取负载
+package throttle
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"clickhouse-ttl-tool/pkg/client"
+)
+
+// minCheckInterval 两次查询负载的最小间隔，并发执行时共享同一次查询结果
+const minCheckInterval = time.Second
+
+// Thresholds 负载阈值，为 0 表示不检查该项
+type Thresholds struct {
+	MaxMerges    int     // 正在进行的合并数（system.merges）
+	MaxMutations int     // 未完成且没有失败记录的 mutation 数（system.mutations）
+	MaxParts     int     // 单个分区的最大 part 数（asynchronous_metrics.MaxPartCountForPartition）
+	MaxPoolUsage float64 // 合并/mutation 后台线程池使用率（0~1，system.metrics）
+}
+
+// Load 服务端当前负载
+type Load struct {
+	Merges    uint64  // 正在进行的合并数
+	Mutations uint64  // 未完成且没有失败记录的 mutation 数（不含卡住的 mutation）
+	MaxParts  float64 // 单个分区的最大 part 数
+	PoolTasks float64 // 后台线程池中正在执行的任务数
+	PoolSize  float64 // 后台线程池大小
+}
+
+// Exceeded 返回超过阈值的负载项描述，未超过时返回空
+func (l Load) Exceeded(th Thresholds) []string {
+	var reasons []string
+	if th.MaxMerges > 0 && l.Merges >= uint64(th.MaxMerges) {
+		reasons = append(reasons, fmt.Sprintf("merges %d >= %d", l.Merges, th.MaxMerges))
+	}
+	if th.MaxMutations > 0 && l.Mutations >= uint64(th.MaxMutations) {
+		reasons = append(reasons, fmt.Sprintf("mutations %d >= %d", l.Mutations, th.MaxMutations))
+	}
+	if th.MaxParts > 0 && l.MaxParts >= float64(th.MaxParts) {
+		reasons = append(reasons, fmt.Sprintf("parts per partition %.0f >= %d", l.MaxParts, th.MaxParts))
+	}
+	if th.MaxPoolUsage > 0 && l.PoolSize > 0 && l.PoolTasks/l.PoolSize >= th.MaxPoolUsage {
+		reasons = append(reasons, fmt.Sprintf("background pool usage %.0f/%.0f >= %.0f%%",
+			l.PoolTasks, l.PoolSize, th.MaxPoolUsage*100))
+	}
+	return reasons
+}
+
+// Options 限流选项
+type Options struct {
+	Thresholds Thresholds    // 负载阈值
+	Interval   time.Duration // 负载超过阈值时的检查间隔
+	MaxWait    time.Duration // 单次最长等待时间，超过后返回错误
+	Pause      time.Duration // 相邻两次 ALTER 的最小间隔（并发的表共享），为 0 表示不停顿
+}
+
+// Throttle 限流器，可被多个 goroutine 并发调用
+type Throttle struct {
+	client *client.Client
+	opts   Options
+
+	mu        sync.Mutex
+	load      Load
+	checkedAt time.Time
+	next      time.Time // 按 Pause 间隔，下一条 ALTER 最早的执行时间
+}
+
+// New 创建限流器
+func New(client *client.Client, opts Options) *Throttle {
+	return &Throttle{
+		client: client,
+		opts:   opts,
+	}
+}
+
+// Wait 等待服务端负载降到阈值以下，再按 Pause 与上一条 ALTER 保持间隔
+// 返回因负载过高而等待的时长（不含固定间隔），超过 MaxWait 仍未降到阈值以下时返回错误
+func (t *Throttle) Wait(ctx context.Context) (time.Duration, error) {
+	waited, err := t.waitLoad(ctx)
+	if err != nil {
+		return waited, err
+	}
+	return waited, t.pause(ctx)
+}
+
+// waitLoad 等待服务端负载降到阈值以下，没有设置阈值时不查询负载
+func (t *Throttle) waitLoad(ctx context.Context) (time.Duration, error) {
+	if t.opts.Thresholds == (Thresholds{}) {
+		return 0, nil
+	}
+
+	var start time.Time
+
+	for {
+		load, err := t.current(ctx)
+		if err != nil {
+			return t.finish(start), err
+		}
+
+		reasons := load.Exceeded(t.opts.Thresholds)
+		if len(reasons) == 0 {
+			return t.finish(start), nil
+		}
+		if start.IsZero() {
+			start = time.Now()
+		} else if time.Since(start) >= t.opts.MaxWait {
+			return t.finish(start), fmt.Errorf("server load still above thresholds after %s: %s",
+				t.opts.MaxWait, strings.Join(reasons, ", "))
+		}
+
+		select {
+		case <-ctx.Done():
+			return t.finish(start), ctx.Err()
+		case <-time.After(t.opts.Interval):
+		}
+	}
+}
+
+// pause 按 Pause 为本次 ALTER 预留执行时间并等待到该时间，第一条 ALTER 不等待
+func (t *Throttle) pause(ctx context.Context) error {
+	if t.opts.Pause <= 0 {
+		return nil
+	}
+
+	t.mu.Lock()
+	at := time.Now()
+	if t.next.After(at) {
+		at = t.next
+	}
+	t.next = at.Add(t.opts.Pause)
+	t.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(time.Until(at)):
+		return nil
+	}
+}
+
+// finish 返回本次等待的时长，start 为零值表示没有等待
+func (t *Throttle) finish(start time.Time) time.Duration {
+	if start.IsZero() {
+		return 0
+	}
+	return time.Since(start)
+}
+
+// current 返回服务端当前负载，距上次查询不足 minCheckInterval 时复用上次结果
+func (t *Throttle) current(ctx context.Context) (Load, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.checkedAt.IsZero() && time.Since(t.checkedAt) < minCheckInterval {
+		return t.load, nil
+	}
+
+	load, err := t.query(ctx)
+	if err != nil {
+		return Load{}, err
+	}
+	t.load = load
+	t.checkedAt = time.Now()
+	return load, nil
+}
+
+// query 查询服务端当前负载
+func (t *Throttle) query(ctx context.Context) (Load, error) {
+	query := `
+		SELECT
+			(SELECT count() FROM system.merges) AS merges,
+			(SELECT count() FROM system.mutations WHERE is_done = 0 AND latest_fail_reason = '') AS mutations,
+			(SELECT toFloat64(max(value)) FROM system.asynchronous_metrics
+			  WHERE metric = 'MaxPartCountForPartition') AS max_parts,
+			(SELECT toFloat64(sum(value)) FROM system.metrics
+			  WHERE metric = 'BackgroundMergesAndMutationsPoolTask') AS pool_tasks,
+			(SELECT toFloat64(sum(value)) FROM system.metrics
+			  WHERE metric = 'BackgroundMergesAndMutationsPoolSize') AS pool_size
+	`
+
+	rows, err := t.client.Query(ctx, query)
+	if err != nil {
+		return Load{}, fmt.Errorf("failed to query server load: %w", err)
+	}
+	if len(rows) == 0 {
+		return Load{}, nil
+	}
+
+	row := rows[0]
+	var load Load
+	load.Merges, _ = row["merges"].(uint64)
+	load.Mutations, _ = row["mutations"].(uint64)
+	load.MaxParts, _ = row["max_parts"].(float64)
+	load.PoolTasks, _ = row["pool_tasks"].(float64)
+	load.PoolSize, _ = row["pool_size"].(float64)
+	return load, nil
+}
@@ -0,0 +1,98 @@
+package throttle
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLoadExceeded(t *testing.T) {
+	defaults := Thresholds{MaxMutations: 8, MaxParts: 300, MaxPoolUsage: 0.9}
+
+	tests := []struct {
+		name string
+		load Load
+		th   Thresholds
+		want []string
+	}{
+		{
+			name: "idle server",
+			load: Load{Merges: 3, Mutations: 1, MaxParts: 12, PoolTasks: 2, PoolSize: 16},
+			th:   defaults,
+		},
+		{
+			name: "mutations at threshold",
+			load: Load{Mutations: 8, PoolSize: 16},
+			th:   defaults,
+			want: []string{"mutations 8 >= 8"},
+		},
+		{
+			name: "parts and pool",
+			load: Load{MaxParts: 412, PoolTasks: 15, PoolSize: 16},
+			th:   defaults,
+			want: []string{"parts per partition 412 >= 300", "background pool usage 15/16 >= 90%"},
+		},
+		{
+			name: "merges checked only when set",
+			load: Load{Merges: 50},
+			th:   Thresholds{MaxMerges: 20},
+			want: []string{"merges 50 >= 20"},
+		},
+		{
+			name: "zero thresholds disable checks",
+			load: Load{Merges: 50, Mutations: 50, MaxParts: 5000, PoolTasks: 16, PoolSize: 16},
+		},
+		{
+			name: "unknown pool size",
+			load: Load{PoolTasks: 16},
+			th:   defaults,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.load.Exceeded(tt.th); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Exceeded() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWaitPause(t *testing.T) {
+	// 未设置阈值时不查询负载，只按间隔停顿
+	th := New(nil, Options{Pause: 20 * time.Millisecond})
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			waited, err := th.Wait(context.Background())
+			if err != nil || waited != 0 {
+				t.Errorf("Wait() = %s, %v, want 0, nil", waited, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// 4 条 ALTER 共享间隔：第一条立即执行，最后一条至少在 3 个间隔之后
+	if elapsed := time.Since(start); elapsed < 60*time.Millisecond {
+		t.Errorf("4 concurrent waits took %s, want at least 60ms", elapsed)
+	}
+}
+
+func TestWaitPauseCanceled(t *testing.T) {
+	th := New(nil, Options{Pause: time.Hour})
+	if _, err := th.Wait(context.Background()); err != nil {
+		t.Fatalf("first Wait() error = %v, want no pause", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := th.Wait(ctx); err == nil {
+		t.Error("Wait() error = nil, want context error")
+	}
+}
@@ -16,3 +16,15 @@ func EscapeIdentifier(identifier string) string {
 	// 用反引号包裹
 	return "`" + escaped + "`"
 }
+
+// EscapeString 转义 ClickHouse 字符串字面量（如磁盘名、卷名）
+// 使用单引号包裹，并转义内部的反斜杠和单引号
+//
+// 示例:
+//   EscapeString("cold") -> "'cold'"
+//   EscapeString("it's") -> "'it\'s'"
+func EscapeString(s string) string {
+	escaped := strings.ReplaceAll(s, `\`, `\\`)
+	escaped = strings.ReplaceAll(escaped, "'", `\'`)
+	return "'" + escaped + "'"
+}